@@ -0,0 +1,164 @@
+package bird
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dev-mockingbird/logf"
+)
+
+// AccessRecord is the structured record of a request
+type AccessRecord struct {
+	Time    time.Time
+	Method  string
+	Route   string
+	Path    string
+	Status  int
+	Latency time.Duration
+	// BytesIn is the Content-Length of the request, the bytes read by the handlers if it's unknown
+	BytesIn   int64
+	BytesOut  int64
+	ClientIP  string
+	RequestId string
+	Principal string
+	UserAgent string
+}
+
+// String formats the record as logfmt
+func (r AccessRecord) String() string {
+	fields := []string{
+		"method=" + r.Method,
+		"route=" + logfmtValue(r.Route),
+		"path=" + logfmtValue(r.Path),
+		"status=" + strconv.Itoa(r.Status),
+		"latency=" + r.Latency.String(),
+		"bytes_in=" + strconv.FormatInt(r.BytesIn, 10),
+		"bytes_out=" + strconv.FormatInt(r.BytesOut, 10),
+		"client_ip=" + r.ClientIP,
+		"request_id=" + logfmtValue(r.RequestId),
+	}
+	if r.Principal != "" {
+		fields = append(fields, "principal="+logfmtValue(r.Principal))
+	}
+	if r.UserAgent != "" {
+		fields = append(fields, "user_agent="+logfmtValue(r.UserAgent))
+	}
+	return strings.Join(fields, " ")
+}
+
+func logfmtValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \"=") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// AccessLogSink receives the access records, the records are logged
+// through Actor.Logger if no sink is configured
+type AccessLogSink func(actor Actor, record AccessRecord)
+
+type accessLogConfig struct {
+	sink     AccessLogSink
+	sampling float64
+	exclude  []string
+}
+
+type AccessLogOption func(*accessLogConfig)
+
+func AccessLogTo(sink AccessLogSink) AccessLogOption {
+	return func(c *accessLogConfig) {
+		c.sink = sink
+	}
+}
+
+// AccessLogSampling logs the rate of the successful requests,
+// the requests failed with 4xx and 5xx are always logged.
+func AccessLogSampling(rate float64) AccessLogOption {
+	return func(c *accessLogConfig) {
+		c.sampling = rate
+	}
+}
+
+// AccessLogExclude skips the paths, a path ending with * excludes the prefix
+func AccessLogExclude(paths ...string) AccessLogOption {
+	return func(c *accessLogConfig) {
+		c.exclude = append(c.exclude, paths...)
+	}
+}
+
+// AccessLog emits one record per request after the handlers are done
+//
+//	r.Use(bird.AccessLog(bird.AccessLogExclude("/healthz", "/metrics"), bird.AccessLogSampling(0.1)))
+func AccessLog(opts ...AccessLogOption) HandleFunc {
+	c := accessLogConfig{
+		sampling: 1,
+		sink: func(actor Actor, record AccessRecord) {
+			actor.Logger().Logf(logf.Info, "access %s", record)
+		},
+	}
+	for _, apply := range opts {
+		apply(&c)
+	}
+	return func(actor Actor) {
+		req := actor.GetRequest()
+		if c.excluded(req.URL.Path) {
+			actor.Next()
+			return
+		}
+		start := time.Now()
+		body := &countingReader{ReadCloser: req.Body}
+		if req.Body != nil {
+			req.Body = body
+		}
+		actor.Next()
+		status := responseStatus(actor)
+		if status < 400 && c.sampling < 1 && rand.Float64() >= c.sampling {
+			return
+		}
+		bytesIn := body.n
+		if req.ContentLength > bytesIn {
+			bytesIn = req.ContentLength
+		}
+		record := AccessRecord{
+			Time:      start,
+			Method:    req.Method,
+			Route:     routePattern(actor),
+			Path:      req.URL.Path,
+			Status:    status,
+			Latency:   time.Since(start),
+			BytesIn:   bytesIn,
+			BytesOut:  responseSize(actor),
+			ClientIP:  ClientIP(actor),
+			RequestId: actor.RequestId(),
+			UserAgent: req.UserAgent(),
+		}
+		if principal, ok := actor.Get(PrincipalKey); ok && principal != nil {
+			record.Principal = fmt.Sprint(principal)
+		}
+		c.sink(actor, record)
+	}
+}
+
+func (c accessLogConfig) excluded(path string) bool {
+	for _, exclude := range c.exclude {
+		if strings.HasSuffix(exclude, "*") && strings.HasPrefix(path, exclude[:len(exclude)-1]) || path == exclude {
+			return true
+		}
+	}
+	return false
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
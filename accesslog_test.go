@@ -0,0 +1,58 @@
+package bird
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		var records []AccessRecord
+		r.Use(AccessLog(AccessLogExclude("/healthz"), AccessLogTo(func(actor Actor, record AccessRecord) {
+			records = append(records, record)
+		})))
+		r.ON("/users/:id", func(actor Actor) {
+			actor.GetResponseWriter().WriteHeader(http.StatusCreated)
+			actor.GetResponseWriter().Write([]byte("created"))
+		}).Prepare(http.MethodPost)
+		r.ON("/upload", func(actor Actor) {
+			io.ReadAll(actor.GetRequest().Body)
+			actor.GetResponseWriter().WriteHeader(http.StatusNoContent)
+		}).Prepare(http.MethodPost)
+		r.ON("/healthz", func(actor Actor) {}).Prepare(http.MethodGet)
+		h := r.HttpHandler()
+
+		// the handler reads none of the body
+		req := httptest.NewRequest(http.MethodPost, "/users/7", strings.NewReader("0123456789"))
+		req.Header.Set("Request-Id", "req-1")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		// the body of unknown length is counted as it's read
+		req = httptest.NewRequest(http.MethodPost, "/upload", io.NopCloser(strings.NewReader("01234")))
+		req.ContentLength = -1
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		if len(records) != 2 {
+			t.Fatal(name, "records", records)
+		}
+		record := records[0]
+		if record.Route != "/users/:id" || record.Path != "/users/7" || record.Status != http.StatusCreated ||
+			record.BytesIn != 10 || record.BytesOut != 7 || record.RequestId != "req-1" {
+			t.Fatal(name, "record", record)
+		}
+		if record = records[1]; record.Status != http.StatusNoContent || record.BytesIn != 5 || record.RequestId == "" {
+			t.Fatal(name, "record of the body read", record)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package bird
 
 import (
+	"io"
+	"io/fs"
 	"net/http"
 
 	"github.com/dev-mockingbird/logf"
@@ -9,6 +11,10 @@ import (
 
 type HandleFunc func(Actor)
 
+// the authentication middlewares set the authenticated principal of the request
+// under PrincipalKey, so that the other middlewares can identify the caller
+const PrincipalKey = "bird.principal"
+
 // sample:
 //
 //	r := GinRouter(engine.RouterGroup, logger.New())
@@ -16,7 +22,22 @@ type HandleFunc func(Actor)
 //	    actor.Write(http.StatusOK, "hello world! ^_^")
 //	}).Prepare()
 type Actor interface {
+	// Bind binds the request into the struct, a field is bound from the first of its path,
+	// query, header, cookie and form tags having the value, from the JSON body by the json tag,
+	// or from the default tag. uri and param are the aliases of path, and the struct is
+	// validated by the binding tags. e.g.
+	//
+	//	var req struct {
+	//	    Id    uuid.UUID     `path:"id"`
+	//	    Limit int           `query:"limit" default:"10"`
+	//	    Since time.Time     `query:"since" layout:"2006-01-02"`
+	//	    Wait  time.Duration `header:"X-Wait"`
+	//	    Name  string        `json:"name"`
+	//	}
 	Bind(data any) error
+	// File returns the first file of the multipart form field, see Uploads
+	File(name string) (*UploadedFile, error)
+	Files(name string) ([]*UploadedFile, error)
 	RequestId() string
 	Set(key string, data any)
 	Get(key string) (any, bool)
@@ -28,6 +49,17 @@ type Actor interface {
 	GetResponseWriter() http.ResponseWriter
 	Validate(data any, rules ...validate.Rules) error
 	Write(statusCode int, data any) error
+	// Stream writes the response by the function, every write is flushed to the client.
+	// nothing is written if the function fails before writing
+	Stream(statusCode int, contentType string, write func(w io.Writer) error) error
+	// ServeFile serves the file with the range and the conditional requests,
+	// nothing is written if the file can't be opened. File is the uploaded file
+	ServeFile(path string) error
+	// Attachment serves the content as a download named name, the ranges are served
+	// only if the content is an io.ReadSeeker
+	Attachment(content io.Reader, name string) error
+	// SSE starts the Server-Sent Events response of the request
+	SSE() *EventStream
 	Logger() logf.Logger
 }
 
@@ -39,5 +71,13 @@ type Router interface {
 	Use(...HandleFunc)
 	Group(string) Router
 	ON(path string, act ...HandleFunc) Entry
+	// WS routes the websocket upgrades of the path on GET, see WebSocket
+	WS(path string, handle func(conn Conn), opts ...WSOption)
+	// Static serves the files of the file system, e.g. an embed.FS, under the prefix on GET and HEAD,
+	// the prefix without the trailing slash redirects to the prefix with it
+	Static(prefix string, fsys fs.FS, opts ...StaticOption)
+	// HttpHandler registers the implicit OPTIONS routes and returns the handler, it can be
+	// called again. the routes prepared after it get their implicit OPTIONS route at once,
+	// so prepare the explicit OPTIONS of a path before it
 	HttpHandler() http.Handler
 }
@@ -5,6 +5,8 @@
 package bird
 
 import (
+	io "io"
+	fs "io/fs"
 	http "net/http"
 	reflect "reflect"
 
@@ -36,6 +38,20 @@ func (m *MockActor) EXPECT() *MockActorMockRecorder {
 	return m.recorder
 }
 
+// Attachment mocks base method.
+func (m *MockActor) Attachment(content io.Reader, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Attachment", content, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Attachment indicates an expected call of Attachment.
+func (mr *MockActorMockRecorder) Attachment(content, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Attachment", reflect.TypeOf((*MockActor)(nil).Attachment), content, name)
+}
+
 // Bind mocks base method.
 func (m *MockActor) Bind(data any) error {
 	m.ctrl.T.Helper()
@@ -50,6 +66,36 @@ func (mr *MockActorMockRecorder) Bind(data interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bind", reflect.TypeOf((*MockActor)(nil).Bind), data)
 }
 
+// File mocks base method.
+func (m *MockActor) File(name string) (*UploadedFile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "File", name)
+	ret0, _ := ret[0].(*UploadedFile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// File indicates an expected call of File.
+func (mr *MockActorMockRecorder) File(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "File", reflect.TypeOf((*MockActor)(nil).File), name)
+}
+
+// Files mocks base method.
+func (m *MockActor) Files(name string) ([]*UploadedFile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Files", name)
+	ret0, _ := ret[0].([]*UploadedFile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Files indicates an expected call of Files.
+func (mr *MockActorMockRecorder) Files(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Files", reflect.TypeOf((*MockActor)(nil).Files), name)
+}
+
 // Get mocks base method.
 func (m *MockActor) Get(key string) (any, bool) {
 	m.ctrl.T.Helper()
@@ -107,6 +153,18 @@ func (mr *MockActorMockRecorder) Logger() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logger", reflect.TypeOf((*MockActor)(nil).Logger))
 }
 
+// Next mocks base method.
+func (m *MockActor) Next() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Next")
+}
+
+// Next indicates an expected call of Next.
+func (mr *MockActorMockRecorder) Next() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Next", reflect.TypeOf((*MockActor)(nil).Next))
+}
+
 // Param mocks base method.
 func (m *MockActor) Param(key string) string {
 	m.ctrl.T.Helper()
@@ -163,6 +221,34 @@ func (mr *MockActorMockRecorder) RequestId() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestId", reflect.TypeOf((*MockActor)(nil).RequestId))
 }
 
+// SSE mocks base method.
+func (m *MockActor) SSE() *EventStream {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SSE")
+	ret0, _ := ret[0].(*EventStream)
+	return ret0
+}
+
+// SSE indicates an expected call of SSE.
+func (mr *MockActorMockRecorder) SSE() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SSE", reflect.TypeOf((*MockActor)(nil).SSE))
+}
+
+// ServeFile mocks base method.
+func (m *MockActor) ServeFile(path string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ServeFile", path)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ServeFile indicates an expected call of ServeFile.
+func (mr *MockActorMockRecorder) ServeFile(path interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ServeFile", reflect.TypeOf((*MockActor)(nil).ServeFile), path)
+}
+
 // Set mocks base method.
 func (m *MockActor) Set(key string, data any) {
 	m.ctrl.T.Helper()
@@ -175,6 +261,20 @@ func (mr *MockActorMockRecorder) Set(key, data interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockActor)(nil).Set), key, data)
 }
 
+// Stream mocks base method.
+func (m *MockActor) Stream(statusCode int, contentType string, write func(io.Writer) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stream", statusCode, contentType, write)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Stream indicates an expected call of Stream.
+func (mr *MockActorMockRecorder) Stream(statusCode, contentType, write interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stream", reflect.TypeOf((*MockActor)(nil).Stream), statusCode, contentType, write)
+}
+
 // Validate mocks base method.
 func (m *MockActor) Validate(data any, rules ...validate.Rules) error {
 	m.ctrl.T.Helper()
@@ -195,9 +295,11 @@ func (mr *MockActorMockRecorder) Validate(data interface{}, rules ...interface{}
 }
 
 // Write mocks base method.
-func (m *MockActor) Write(statusCode int, data any) {
+func (m *MockActor) Write(statusCode int, data any) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Write", statusCode, data)
+	ret := m.ctrl.Call(m, "Write", statusCode, data)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // Write indicates an expected call of Write.
@@ -268,6 +370,20 @@ func (m *MockRouter) EXPECT() *MockRouterMockRecorder {
 	return m.recorder
 }
 
+// Group mocks base method.
+func (m *MockRouter) Group(arg0 string) Router {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Group", arg0)
+	ret0, _ := ret[0].(Router)
+	return ret0
+}
+
+// Group indicates an expected call of Group.
+func (mr *MockRouterMockRecorder) Group(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Group", reflect.TypeOf((*MockRouter)(nil).Group), arg0)
+}
+
 // HttpHandler mocks base method.
 func (m *MockRouter) HttpHandler() http.Handler {
 	m.ctrl.T.Helper()
@@ -301,6 +417,23 @@ func (mr *MockRouterMockRecorder) ON(path interface{}, act ...interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ON", reflect.TypeOf((*MockRouter)(nil).ON), varargs...)
 }
 
+// Static mocks base method.
+func (m *MockRouter) Static(prefix string, fsys fs.FS, opts ...StaticOption) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{prefix, fsys}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "Static", varargs...)
+}
+
+// Static indicates an expected call of Static.
+func (mr *MockRouterMockRecorder) Static(prefix, fsys interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{prefix, fsys}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Static", reflect.TypeOf((*MockRouter)(nil).Static), varargs...)
+}
+
 // Use mocks base method.
 func (m *MockRouter) Use(arg0 ...HandleFunc) {
 	m.ctrl.T.Helper()
@@ -316,3 +449,20 @@ func (mr *MockRouterMockRecorder) Use(arg0 ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Use", reflect.TypeOf((*MockRouter)(nil).Use), arg0...)
 }
+
+// WS mocks base method.
+func (m *MockRouter) WS(path string, handle func(Conn), opts ...WSOption) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{path, handle}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "WS", varargs...)
+}
+
+// WS indicates an expected call of WS.
+func (mr *MockRouterMockRecorder) WS(path, handle interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{path, handle}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WS", reflect.TypeOf((*MockRouter)(nil).WS), varargs...)
+}
@@ -0,0 +1,306 @@
+package bird
+
+import (
+	"encoding"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	birderrors "github.com/dev-mockingbird/errors"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// the sources of the bind tags, from the lowest precedence to the highest
+var bindSources = []string{"form", "cookie", "header", "query", "path"}
+
+// the tags of the path parameters of gin and echo are the aliases of path
+var pathAliases = []string{"path", "uri", "param"}
+
+type bindSource struct {
+	tag  string
+	name string
+}
+
+type bindField struct {
+	index   []int
+	sources []bindSource
+	def     string
+	layout  string
+}
+
+var bindFields sync.Map // reflect.Type => []bindField
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// fieldsOf collects the fields with the bind tags, the embedded structs are flattened
+func fieldsOf(t reflect.Type) []bindField {
+	if fields, ok := bindFields.Load(t); ok {
+		return fields.([]bindField)
+	}
+	var fields []bindField
+	var collect func(t reflect.Type, index []int)
+	collect = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			idx := append(append([]int{}, index...), i)
+			if f.Anonymous && f.Type.Kind() == reflect.Struct && !isScalar(f.Type) {
+				collect(f.Type, idx)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			field := bindField{index: idx, def: f.Tag.Get("default"), layout: f.Tag.Get("layout")}
+			for _, tag := range bindSources {
+				if name := tagName(f.Tag, tag); name != "" {
+					field.sources = append(field.sources, bindSource{tag: tag, name: name})
+				}
+			}
+			if len(field.sources) > 0 || field.def != "" {
+				fields = append(fields, field)
+			}
+		}
+	}
+	collect(t, nil)
+	bindFields.Store(t, fields)
+	return fields
+}
+
+// tagName returns the name of the source tag, the path tag falls back to its aliases
+func tagName(tag reflect.StructTag, source string) string {
+	tags := []string{source}
+	if source == "path" {
+		tags = pathAliases
+	}
+	for _, t := range tags {
+		if name := strings.Split(tag.Get(t), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return ""
+}
+
+// bind implements Actor.Bind. the fields are bound from the sources of their tags,
+// path, query, header, cookie and form, in the order of precedence, the body is decoded
+// as JSON if it's JSON, and the default tag is applied if no source has the value.
+// the struct is validated by the binding tags as gin does at last.
+func bind(actor Actor, obj any) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind %T: a pointer to a struct is required", obj)
+	}
+	v = v.Elem()
+	fields := fieldsOf(v.Type())
+	for _, f := range fields {
+		if f.def == "" {
+			continue
+		}
+		if err := setField(v.FieldByIndex(f.index), splitDefault(v.FieldByIndex(f.index), f.def), f.layout); err != nil {
+			return birderrors.New(fmt.Sprintf("default of %s: %s", v.Type().FieldByIndex(f.index).Name, err.Error()), CodeInvalidArguments)
+		}
+	}
+	req := actor.GetRequest()
+	if err := prepareBody(actor, req); err != nil {
+		return err
+	}
+	if err := decodeBody(actor, req, obj); err != nil {
+		return err
+	}
+	if err := parseForm(actor, req, fields); err != nil {
+		return err
+	}
+	values := bindValues(actor, req)
+	for _, f := range fields {
+		for _, source := range precedence(f.sources) {
+			vals, ok := values(source)
+			if !ok {
+				continue
+			}
+			if err := setField(v.FieldByIndex(f.index), vals, f.layout); err != nil {
+				return birderrors.New(fmt.Sprintf("%s %s: %s", source.tag, source.name, err.Error()), CodeInvalidArguments)
+			}
+			break
+		}
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	if err := binding.Validator.ValidateStruct(obj); err != nil {
+		return birderrors.New(err.Error(), CodeInvalidArguments)
+	}
+	return nil
+}
+
+// precedence sorts the sources of a field from the highest precedence to the lowest
+func precedence(sources []bindSource) []bindSource {
+	ret := make([]bindSource, len(sources))
+	for i, s := range sources {
+		ret[len(sources)-1-i] = s
+	}
+	return ret
+}
+
+func splitDefault(field reflect.Value, def string) []string {
+	if field.Kind() == reflect.Slice && !field.Type().Implements(textUnmarshalerType) {
+		return strings.Split(def, ",")
+	}
+	return []string{def}
+}
+
+// decodeBody decodes the JSON body into the object, see BindOptions
+func decodeBody(actor Actor, req *http.Request, obj any) error {
+	if req.Body == nil || req.Body == http.NoBody || req.ContentLength == 0 {
+		return nil
+	}
+	if typ, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); typ != "application/json" && !strings.HasSuffix(typ, "+json") {
+		return nil
+	}
+	return decodeJSON(bindConfigOf(actor), req.Body, obj)
+}
+
+// parseForm parses the form if a field is bound from it
+func parseForm(actor Actor, req *http.Request, fields []bindField) error {
+	for _, f := range fields {
+		for _, source := range f.sources {
+			if source.tag != "form" {
+				continue
+			}
+			var err error
+			if typ, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); typ == "multipart/form-data" {
+				err = req.ParseMultipartForm(uploadConfigOf(actor).maxMemory)
+			} else {
+				err = req.ParseForm()
+			}
+			if err != nil {
+				return bodyError(err)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// bindValues returns the values of a source of the request
+func bindValues(actor Actor, req *http.Request) func(bindSource) ([]string, bool) {
+	var query map[string][]string
+	return func(s bindSource) ([]string, bool) {
+		switch s.tag {
+		case "path":
+			if v := actor.Param(s.name); v != "" {
+				return []string{v}, true
+			}
+		case "query":
+			if query == nil {
+				query = req.URL.Query()
+			}
+			v, ok := query[s.name]
+			return v, ok && len(v) > 0
+		case "header":
+			v := req.Header.Values(s.name)
+			return v, len(v) > 0
+		case "cookie":
+			if c, err := req.Cookie(s.name); err == nil {
+				return []string{c.Value}, true
+			}
+		case "form":
+			v, ok := req.Form[s.name]
+			return v, ok && len(v) > 0
+		}
+		return nil, false
+	}
+}
+
+func isScalar(t reflect.Type) bool {
+	return t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// setField converts the values to the type of the field, the slices take all the values
+func setField(field reflect.Value, values []string, layout string) error {
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return setField(field.Elem(), values, layout)
+	}
+	if field.Kind() == reflect.Slice && !isScalar(field.Type()) {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), value, layout); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	return setValue(field, values[0], layout)
+}
+
+func setValue(v reflect.Value, value, layout string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), value, layout)
+	}
+	switch {
+	case v.Type() == timeType:
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			return fmt.Errorf("%q isn't a time of %s", value, layout)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q isn't a duration", value)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType):
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q isn't a boolean", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q isn't an integer", value)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q isn't an unsigned integer", value)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q isn't a number", value)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("can't bind %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package bird
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+)
+
+type bindRequest struct {
+	Id      uuid.UUID     `path:"id"`
+	Limit   int           `query:"limit" default:"10"`
+	Tags    []string      `query:"tag"`
+	Since   time.Time     `query:"since" layout:"2006-01-02"`
+	Wait    time.Duration `header:"X-Wait"`
+	Tenant  string        `query:"tenant" header:"X-Tenant"`
+	Session string        `cookie:"session"`
+	Name    string        `json:"name"`
+}
+
+func TestBind(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		var got bindRequest
+		var bindErr error
+		r.ON("/users/:id", func(actor Actor) {
+			got = bindRequest{}
+			bindErr = actor.Bind(&got)
+		}).Prepare(http.MethodPost)
+		id := uuid.New()
+		req := httptest.NewRequest(http.MethodPost, "/users/"+id.String()+"?tag=a&tag=b&since=2023-01-02&tenant=q", strings.NewReader(`{"name":"bird"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Wait", "1m")
+		req.Header.Set("X-Tenant", "h")
+		req.AddCookie(&http.Cookie{Name: "session", Value: "s"})
+		r.HttpHandler().ServeHTTP(httptest.NewRecorder(), req)
+		expected := bindRequest{
+			Id: id, Limit: 10, Tags: []string{"a", "b"}, Since: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+			Wait: time.Minute, Tenant: "q", Session: "s", Name: "bird",
+		}
+		if bindErr != nil || got.Id != expected.Id || got.Limit != 10 || strings.Join(got.Tags, ",") != "a,b" ||
+			!got.Since.Equal(expected.Since) || got.Wait != time.Minute || got.Tenant != "q" || got.Session != "s" || got.Name != "bird" {
+			t.Fatal(name, "unexpected binding", got, bindErr)
+		}
+		r.HttpHandler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users/"+id.String()+"?limit=ten", nil))
+		if bindErr == nil || !strings.Contains(bindErr.Error(), "query limit") {
+			t.Fatal(name, "invalid value bound", bindErr)
+		}
+	}
+}
+
+func TestBindCompat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	type item struct {
+		Id    int    `uri:"id"`
+		Name  string `param:"name"`
+		Title string `json:"title" binding:"required"`
+	}
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		var got item
+		var bindErr error
+		r.ON("/items/:id/:name", func(actor Actor) {
+			got = item{}
+			bindErr = actor.Bind(&got)
+		}).Prepare(http.MethodPost)
+		post := func(body string) {
+			req := httptest.NewRequest(http.MethodPost, "/items/7/seed", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			r.HttpHandler().ServeHTTP(httptest.NewRecorder(), req)
+		}
+		post(`{"title":"t"}`)
+		if bindErr != nil || got != (item{Id: 7, Name: "seed", Title: "t"}) {
+			t.Fatal(name, "uri and param tags not bound", got, bindErr)
+		}
+		post(`{}`)
+		if bindErr == nil || ErrorOccurred(bindErr, CodeUnkownError).Code != CodeInvalidArguments || !strings.Contains(bindErr.Error(), "required") {
+			t.Fatal(name, "binding tags not validated", bindErr)
+		}
+	}
+}
+
+func TestBindOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := GinRouter(gin.New(), logf.New())
+	r.Use(BindOptions(MaxBodySize(64)))
+	r.ON("/strict", BindOptions(StrictJSON(), MaxJSONDepth(2)), func(actor Actor) {
+		var req struct {
+			Name string         `json:"name"`
+			Meta map[string]any `json:"meta,omitempty"`
+		}
+		GetForwarder().Forward(actor, &req, func() error {
+			actor.Write(http.StatusOK, OK(req.Name))
+			return nil
+		})
+	}).Prepare(http.MethodPost)
+	post := func(body []byte, encoding string) int {
+		req := httptest.NewRequest(http.MethodPost, "/strict", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", encoding)
+		rec := httptest.NewRecorder()
+		r.HttpHandler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(`{"name":"bird"}`))
+	w.Close()
+	enc, _ := zstd.NewWriter(nil)
+	bomb := enc.EncodeAll([]byte(`{"name":"`+strings.Repeat("x", 1024)+`"}`), nil)
+	for _, c := range []struct {
+		body     []byte
+		encoding string
+		status   int
+	}{
+		{[]byte(`{"name":"bird"}`), "", http.StatusOK},
+		{gz.Bytes(), "gzip", http.StatusOK},
+		{enc.EncodeAll([]byte(`{"name":"bird"}`), nil), "zstd", http.StatusOK},
+		{[]byte(`{"name":"bird","age":1}`), "", http.StatusBadRequest},
+		{[]byte(`{"name":"bird","name":"cat"}`), "", http.StatusBadRequest},
+		{[]byte(`{"name":"bird"} {}`), "", http.StatusBadRequest},
+		{[]byte(`{"meta":{"a":{"b":1}}}`), "", http.StatusBadRequest},
+		{[]byte(`{"name":"` + strings.Repeat("x", 64) + `"}`), "", http.StatusRequestEntityTooLarge},
+		{bomb, "zstd", http.StatusRequestEntityTooLarge},
+	} {
+		if status := post(c.body, c.encoding); status != c.status {
+			t.Fatal("unexpected status", status, c.encoding, string(c.body))
+		}
+	}
+}
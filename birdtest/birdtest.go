@@ -0,0 +1,212 @@
+// Package birdtest tests the bird handlers without gomock expectations or sockets,
+// the handlers run on a real actor and the responses are recorded in memory.
+//
+//	actor, rec := birdtest.NewActor(http.MethodPost, "/orders/7", order, birdtest.Param("id", "7"))
+//	handler(actor)
+//	rec.AssertCode(t, bird.CodeOK)
+package birdtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dev-mockingbird/bird"
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+)
+
+type options struct {
+	header  http.Header
+	cookies []*http.Cookie
+	params  gin.Params
+	values  map[string]any
+	logger  logf.Logger
+}
+
+type Option func(*options)
+
+func Header(key, value string) Option {
+	return func(o *options) {
+		o.header.Add(key, value)
+	}
+}
+
+func Cookie(cookie *http.Cookie) Option {
+	return func(o *options) {
+		o.cookies = append(o.cookies, cookie)
+	}
+}
+
+// Param sets the path parameter of NewActor, the router sets them for Client
+func Param(key, value string) Option {
+	return func(o *options) {
+		o.params = append(o.params, gin.Param{Key: key, Value: value})
+	}
+}
+
+// Set sets the value on the actor of NewActor as a middleware would, e.g. the principal
+func Set(key string, value any) Option {
+	return func(o *options) {
+		o.values[key] = value
+	}
+}
+
+// Logger is the logger of the actor of NewActor, logf.New() by default
+func Logger(logger logf.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{header: http.Header{}, values: map[string]any{}}
+	for _, apply := range opts {
+		apply(o)
+	}
+	return o
+}
+
+// newRequest creates the request of the body, the readers, strings and bytes are sent as is,
+// the other bodies are sent as JSON
+func newRequest(method, path string, body any, o *options) *http.Request {
+	var r io.Reader
+	contentType := ""
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		r = b
+	case string:
+		r = strings.NewReader(b)
+	case []byte:
+		r = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			panic(err)
+		}
+		r, contentType = bytes.NewReader(data), "application/json"
+	}
+	req := httptest.NewRequest(method, path, r)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for key, values := range o.header {
+		req.Header[key] = values
+	}
+	for _, cookie := range o.cookies {
+		req.AddCookie(cookie)
+	}
+	return req
+}
+
+// NewActor returns an actor of the request and the recorder of its response, the body is
+// sent as JSON unless it's an io.Reader, a string or bytes. Actor.Next does nothing
+func NewActor(method, path string, body any, opts ...Option) (bird.Actor, *Recorder) {
+	o := newOptions(opts)
+	rec := &Recorder{ResponseRecorder: httptest.NewRecorder()}
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = newRequest(method, path, body, o)
+	ctx.Params = o.params
+	logger := o.logger
+	if logger == nil {
+		logger = logf.New()
+	}
+	actor := bird.GinActor(ctx, logger)
+	for key, value := range o.values {
+		actor.Set(key, value)
+	}
+	return actor, rec
+}
+
+// Recorder records the response
+type Recorder struct {
+	*httptest.ResponseRecorder
+}
+
+// Decode decodes the response body
+func (r *Recorder) Decode(out any) error {
+	return json.Unmarshal(r.Body.Bytes(), out)
+}
+
+// DecodeData decodes the data of the bird.ResponseBody
+func (r *Recorder) DecodeData(out any) error {
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := r.Decode(&body); err != nil {
+		return err
+	}
+	return json.Unmarshal(body.Data, out)
+}
+
+// ResponseCode returns the code of the bird.ResponseBody, empty if the body isn't one
+func (r *Recorder) ResponseCode() string {
+	var body bird.ResponseBody
+	if err := r.Decode(&body); err != nil {
+		return ""
+	}
+	return body.Code
+}
+
+// AssertCode fails the test if the code of the bird.ResponseBody isn't the code
+func (r *Recorder) AssertCode(t testing.TB, code string) {
+	t.Helper()
+	if got := r.ResponseCode(); got != code {
+		t.Fatalf("response code %q, expected %q: %s", got, code, r.Body.String())
+	}
+}
+
+// AssertStatus fails the test if the status of the response isn't the status
+func (r *Recorder) AssertStatus(t testing.TB, status int) {
+	t.Helper()
+	if r.Code != status {
+		t.Fatalf("response status %d, expected %d: %s", r.Code, status, r.Body.String())
+	}
+}
+
+// TestClient sends the requests to the handler of the router in memory, the middlewares
+// and the routing run as they do on the server
+type TestClient struct {
+	handler http.Handler
+}
+
+// Client returns the test client of the router
+//
+//	c := birdtest.Client(router)
+//	rec := c.Post("/orders", order, birdtest.Header("Authorization", "Bearer "+token))
+//	rec.AssertStatus(t, http.StatusCreated)
+func Client(router bird.Router) *TestClient {
+	return &TestClient{handler: router.HttpHandler()}
+}
+
+// Do sends the request, the body is sent as NewActor does. Param, Set and Logger are ignored
+func (c *TestClient) Do(method, path string, body any, opts ...Option) *Recorder {
+	rec := &Recorder{ResponseRecorder: httptest.NewRecorder()}
+	c.handler.ServeHTTP(rec, newRequest(method, path, body, newOptions(opts)))
+	return rec
+}
+
+func (c *TestClient) Get(path string, opts ...Option) *Recorder {
+	return c.Do(http.MethodGet, path, nil, opts...)
+}
+
+func (c *TestClient) Post(path string, body any, opts ...Option) *Recorder {
+	return c.Do(http.MethodPost, path, body, opts...)
+}
+
+func (c *TestClient) Put(path string, body any, opts ...Option) *Recorder {
+	return c.Do(http.MethodPut, path, body, opts...)
+}
+
+func (c *TestClient) Patch(path string, body any, opts ...Option) *Recorder {
+	return c.Do(http.MethodPatch, path, body, opts...)
+}
+
+func (c *TestClient) Delete(path string, opts ...Option) *Recorder {
+	return c.Do(http.MethodDelete, path, nil, opts...)
+}
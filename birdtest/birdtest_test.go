@@ -0,0 +1,77 @@
+package birdtest_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/dev-mockingbird/bird"
+	"github.com/dev-mockingbird/bird/birdtest"
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+type order struct {
+	Id     int    `path:"id" json:"id"`
+	Tenant string `header:"X-Tenant" json:"tenant"`
+	Item   string `json:"item"`
+}
+
+func update(actor bird.Actor) {
+	var o order
+	if err := actor.Bind(&o); err != nil {
+		actor.Write(http.StatusBadRequest, bird.InvalidArguments(err))
+		return
+	}
+	if principal, _ := actor.Get(bird.PrincipalKey); principal != "bird" {
+		actor.Write(http.StatusUnauthorized, bird.Unauthorized(nil))
+		return
+	}
+	actor.Write(http.StatusOK, bird.OK(o))
+}
+
+func TestNewActor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	actor, rec := birdtest.NewActor(http.MethodPut, "/orders/7", map[string]string{"item": "seed"},
+		birdtest.Param("id", "7"), birdtest.Header("X-Tenant", "nest"), birdtest.Set(bird.PrincipalKey, "bird"))
+	update(actor)
+	rec.AssertStatus(t, http.StatusOK)
+	rec.AssertCode(t, bird.CodeOK)
+	var got order
+	if err := rec.DecodeData(&got); err != nil || got != (order{Id: 7, Tenant: "nest", Item: "seed"}) {
+		t.Fatal("unexpected data", got, err)
+	}
+	if rec.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Fatal("unexpected content type", rec.Header())
+	}
+
+	actor, rec = birdtest.NewActor(http.MethodPut, "/orders/seven", "{}", birdtest.Param("id", "seven"))
+	update(actor)
+	rec.AssertStatus(t, http.StatusBadRequest)
+	rec.AssertCode(t, bird.CodeInvalidArguments)
+}
+
+func TestClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]bird.Router{
+		"gin":  bird.GinRouter(gin.New(), logf.New()),
+		"echo": bird.EchoRouter(echo.New(), logf.New()),
+	} {
+		r.Use(func(actor bird.Actor) {
+			if actor.GetRequest().Header.Get("Authorization") == "Bearer bird" {
+				actor.Set(bird.PrincipalKey, "bird")
+			}
+			actor.Next()
+		})
+		r.ON("/orders/:id", update).Prepare(http.MethodPut)
+		c := birdtest.Client(r)
+
+		rec := c.Put("/orders/7", order{Item: "seed"}, birdtest.Header("Authorization", "Bearer bird"))
+		rec.AssertCode(t, bird.CodeOK)
+		var got order
+		if err := rec.DecodeData(&got); err != nil || got.Id != 7 || got.Item != "seed" {
+			t.Fatal(name, "unexpected data", got, err)
+		}
+		c.Put("/orders/7", order{}).AssertStatus(t, http.StatusUnauthorized)
+	}
+}
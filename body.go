@@ -0,0 +1,275 @@
+package bird
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	birderrors "github.com/dev-mockingbird/errors"
+	"github.com/dev-mockingbird/logf"
+	"github.com/klauspost/compress/zstd"
+)
+
+const bindConfigKey = "bird.bind-config"
+
+var ErrPayloadTooLarge = birderrors.New("payload too large", CodePayloadTooLarge)
+
+type bindConfig struct {
+	disallowUnknownFields bool
+	disallowDuplicateKeys bool
+	disallowTrailingData  bool
+	maxBodySize           int64
+	maxDepth              int
+}
+
+type BindOption func(*bindConfig)
+
+// DisallowUnknownFields rejects the JSON objects with the fields the struct doesn't have
+func DisallowUnknownFields() BindOption {
+	return func(c *bindConfig) {
+		c.disallowUnknownFields = true
+	}
+}
+
+// DisallowDuplicateKeys rejects the JSON objects with a key more than once
+func DisallowDuplicateKeys() BindOption {
+	return func(c *bindConfig) {
+		c.disallowDuplicateKeys = true
+	}
+}
+
+// DisallowTrailingData rejects the JSON bodies with anything after the value
+func DisallowTrailingData() BindOption {
+	return func(c *bindConfig) {
+		c.disallowTrailingData = true
+	}
+}
+
+// StrictJSON rejects the unknown fields, the duplicate keys and the trailing data
+func StrictJSON() BindOption {
+	return func(c *bindConfig) {
+		c.disallowUnknownFields = true
+		c.disallowDuplicateKeys = true
+		c.disallowTrailingData = true
+	}
+}
+
+// MaxBodySize limits the size of the body, it's checked after decompression as well
+func MaxBodySize(bytes int64) BindOption {
+	return func(c *bindConfig) {
+		c.maxBodySize = bytes
+	}
+}
+
+// MaxJSONDepth limits the nesting depth of the JSON objects and arrays
+func MaxJSONDepth(depth int) BindOption {
+	return func(c *bindConfig) {
+		c.maxDepth = depth
+	}
+}
+
+// BindOptions configures Actor.Bind for the handlers after it, the options of a route
+// are applied on top of the ones of the router. the requests declaring a body larger
+// than the max body size are rejected with 413.
+//
+//	r.Use(bird.BindOptions(bird.MaxBodySize(1<<20)))
+//	r.ON("/orders", bird.BindOptions(bird.StrictJSON(), bird.MaxJSONDepth(8)), create).Prepare(http.MethodPost)
+func BindOptions(opts ...BindOption) HandleFunc {
+	return func(actor Actor) {
+		c := *bindConfigOf(actor)
+		for _, apply := range opts {
+			apply(&c)
+		}
+		actor.Set(bindConfigKey, &c)
+		if c.maxBodySize > 0 && actor.GetRequest().ContentLength > c.maxBodySize {
+			actor.Logger().Logf(logf.Trace, "reject body of %d bytes", actor.GetRequest().ContentLength)
+			actor.Write(http.StatusRequestEntityTooLarge, PayloadTooLarge(ErrPayloadTooLarge))
+			return
+		}
+		actor.Next()
+	}
+}
+
+func bindConfigOf(actor Actor) *bindConfig {
+	if c, ok := actor.Get(bindConfigKey); ok {
+		if c, ok := c.(*bindConfig); ok {
+			return c
+		}
+	}
+	return &bindConfig{}
+}
+
+// BindErrorStatus returns the status of the response to an error of Actor.Bind
+func BindErrorStatus(err error) int {
+	if isPayloadTooLarge(err) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// isPayloadTooLarge reports whether the error is tagged payload-too-large,
+// the tagged errors can't be compared
+func isPayloadTooLarge(err error) bool {
+	for _, tag := range birderrors.Tags(birderrors.LastTagged(err)) {
+		if tag == CodePayloadTooLarge {
+			return true
+		}
+	}
+	return false
+}
+
+// decompressedBody decodes the gzip and zstd bodies
+type decompressedBody struct {
+	io.Reader
+	close func() error
+}
+
+func (b decompressedBody) Close() error {
+	return b.close()
+}
+
+// prepareBody limits the size of the body and decompresses it, once for a request
+func prepareBody(actor Actor, req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	c := bindConfigOf(actor)
+	encoding := strings.ToLower(strings.TrimSpace(req.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" {
+		if c.maxBodySize > 0 {
+			req.Body = http.MaxBytesReader(actor.GetResponseWriter(), req.Body, c.maxBodySize)
+		}
+		return nil
+	}
+	body := req.Body
+	if c.maxBodySize > 0 {
+		body = http.MaxBytesReader(actor.GetResponseWriter(), body, c.maxBodySize)
+	}
+	var r io.Reader
+	closeBody := body.Close
+	switch encoding {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return bodyError(err)
+		}
+		r = gz
+	case "zstd":
+		zr, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return bodyError(err)
+		}
+		r = zr
+		closeBody = func() error {
+			zr.Close()
+			return body.Close()
+		}
+	default:
+		return birderrors.New(fmt.Sprintf("content encoding %s isn't supported", encoding), CodeBadFormat)
+	}
+	if c.maxBodySize > 0 {
+		r = &limitedReader{r: r, n: c.maxBodySize, err: ErrPayloadTooLarge}
+	}
+	req.Body = decompressedBody{Reader: r, close: closeBody}
+	req.Header.Del("Content-Encoding")
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+	return nil
+}
+
+func bodyError(err error) error {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case len(birderrors.Tags(err)) > 0:
+		return err
+	case errors.As(err, &tooLarge):
+		return ErrPayloadTooLarge
+	}
+	return birderrors.Tag(err, CodeBadFormat)
+}
+
+// decodeJSON decodes the JSON body with the checks of the config
+func decodeJSON(c *bindConfig, body io.Reader, obj any) error {
+	if c.disallowDuplicateKeys || c.maxDepth > 0 {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return bodyError(err)
+		}
+		if err := checkJSON(data, c.maxDepth, c.disallowDuplicateKeys); err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	dec := json.NewDecoder(body)
+	if c.disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(obj); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		if e := bodyError(err); isPayloadTooLarge(e) {
+			return e
+		}
+		return birderrors.New(fmt.Sprintf("json: %s", err.Error()), CodeBadFormat)
+	}
+	if c.disallowTrailingData {
+		if _, err := dec.Token(); err != io.EOF {
+			return birderrors.New("json: unexpected data after the value", CodeBadFormat)
+		}
+	}
+	return nil
+}
+
+// checkJSON checks the nesting depth and the duplicate keys of the JSON
+func checkJSON(data []byte, maxDepth int, disallowDuplicateKeys bool) error {
+	type frame struct {
+		object    bool
+		expectKey bool
+		keys      map[string]bool
+	}
+	var stack []*frame
+	value := func() {
+		if n := len(stack); n > 0 && stack[n-1].object {
+			stack[n-1].expectKey = true
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return birderrors.New(fmt.Sprintf("json: %s", err.Error()), CodeBadFormat)
+		}
+		if n := len(stack); n > 0 && stack[n-1].object && stack[n-1].expectKey {
+			if key, ok := tok.(string); ok {
+				top := stack[n-1]
+				if disallowDuplicateKeys && top.keys[key] {
+					return birderrors.New(fmt.Sprintf("json: duplicate key %q", key), CodeBadFormat)
+				}
+				top.keys[key], top.expectKey = true, false
+				continue
+			}
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			stack = append(stack, &frame{object: tok == json.Delim('{'), expectKey: true, keys: make(map[string]bool)})
+			if maxDepth > 0 && len(stack) > maxDepth {
+				return birderrors.New(fmt.Sprintf("json: nesting deeper than %d", maxDepth), CodeBadFormat)
+			}
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			value()
+		default:
+			value()
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package bird
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dev-mockingbird/errors"
+	"github.com/dev-mockingbird/logf"
+	"github.com/dev-mockingbird/validate"
+	microerrors "go-micro.dev/v4/errors"
+)
+
+// ConcurrencyLimiter bounds the requests in flight
+type ConcurrencyLimiter interface {
+	// Acquire reserves a slot, it reports false when the limit is reached.
+	// release must be called when the request is done with its latency
+	// and whether it was dropped (failed because of overload)
+	Acquire() (release func(latency time.Duration, dropped bool), ok bool)
+	Limit() int
+	InFlight() int
+}
+
+type fixedConcurrency struct {
+	limit    int64
+	inFlight int64
+}
+
+// FixedConcurrency allows at most limit requests in flight, the limit must be at least 1
+func FixedConcurrency(limit int) ConcurrencyLimiter {
+	if limit < 1 {
+		panic(fmt.Sprintf("bird: concurrency limit %d is less than 1", limit))
+	}
+	return &fixedConcurrency{limit: int64(limit)}
+}
+
+func (l *fixedConcurrency) Acquire() (func(time.Duration, bool), bool) {
+	if atomic.AddInt64(&l.inFlight, 1) > l.limit {
+		atomic.AddInt64(&l.inFlight, -1)
+		return nil, false
+	}
+	var once sync.Once
+	return func(time.Duration, bool) {
+		once.Do(func() {
+			atomic.AddInt64(&l.inFlight, -1)
+		})
+	}, true
+}
+
+func (l *fixedConcurrency) Limit() int {
+	return int(l.limit)
+}
+
+func (l *fixedConcurrency) InFlight() int {
+	return int(atomic.LoadInt64(&l.inFlight))
+}
+
+// adaptiveConcurrency adjusts the limit by every released sample, the limit never
+// drops below 1 so that the successful requests can raise it again
+type adaptiveConcurrency struct {
+	mu       sync.Mutex
+	limit    float64
+	min, max float64
+	inFlight int
+	update   func(limit float64, inFlight int, latency time.Duration, dropped bool) float64
+}
+
+func newAdaptiveConcurrency(initial, min, max int, update func(float64, int, time.Duration, bool) float64) *adaptiveConcurrency {
+	floor := math.Max(1, float64(min))
+	if min < 0 || float64(max) < floor || float64(initial) < floor || initial > max {
+		panic(fmt.Sprintf("bird: concurrency limit initial %d, min %d, max %d requires 1 <= max(1, min) <= initial <= max", initial, min, max))
+	}
+	return &adaptiveConcurrency{limit: float64(initial), min: floor, max: float64(max), update: update}
+}
+
+func (l *adaptiveConcurrency) Acquire() (func(time.Duration, bool), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight >= int(l.limit) {
+		return nil, false
+	}
+	l.inFlight++
+	var once sync.Once
+	return func(latency time.Duration, dropped bool) {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			limit := l.update(l.limit, l.inFlight, latency, dropped)
+			l.limit = math.Max(l.min, math.Min(l.max, limit))
+			l.inFlight--
+		})
+	}, true
+}
+
+func (l *adaptiveConcurrency) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+func (l *adaptiveConcurrency) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// AIMDConcurrency increases the limit by one for the successful requests while
+// the limit is utilized, and backs off by 10% when a request is dropped or
+// slower than timeout. the limit stays between max(1, min) and max.
+func AIMDConcurrency(initial, min, max int, timeout time.Duration) ConcurrencyLimiter {
+	return newAdaptiveConcurrency(initial, min, max,
+		func(limit float64, inFlight int, latency time.Duration, dropped bool) float64 {
+			if dropped || timeout > 0 && latency > timeout {
+				return limit * 0.9
+			}
+			// don't grow the limit when the traffic doesn't reach it
+			if float64(inFlight)*2 < limit {
+				return limit
+			}
+			return limit + 1
+		})
+}
+
+// GradientConcurrency tracks the long term latency, the limit shrinks as the
+// latency grows over it and grows by sqrt(limit) while the latency is stable.
+// the limit stays between max(1, min) and max.
+func GradientConcurrency(initial, min, max int) ConcurrencyLimiter {
+	var longLatency float64
+	return newAdaptiveConcurrency(initial, min, max,
+		func(limit float64, inFlight int, latency time.Duration, dropped bool) float64 {
+			if dropped {
+				return limit * 0.9
+			}
+			short := float64(latency)
+			if short <= 0 {
+				return limit
+			}
+			if longLatency == 0 {
+				longLatency = short
+			}
+			longLatency = longLatency*0.95 + short*0.05
+			if float64(inFlight)*2 < limit {
+				return limit
+			}
+			gradient := math.Max(0.5, math.Min(1, longLatency/short))
+			next := limit*gradient + math.Sqrt(limit)
+			return limit*0.8 + next*0.2
+		})
+}
+
+// ConcurrencyLimit rejects the requests with 503 when the limiter is saturated,
+// use it on a router for a shared limit or on a route for its own.
+//
+//	limiter := bird.AIMDConcurrency(50, 10, 500, time.Second)
+//	r.ON("/orders", bird.ConcurrencyLimit(limiter), createOrder).Prepare(http.MethodPost)
+func ConcurrencyLimit(limiter ConcurrencyLimiter) HandleFunc {
+	return func(actor Actor) {
+		release, ok := limiter.Acquire()
+		if !ok {
+			overloaded(actor, limiter)
+			return
+		}
+		start := time.Now()
+		defer func() {
+			release(time.Since(start), responseStatus(actor) == http.StatusServiceUnavailable)
+		}()
+		actor.Next()
+	}
+}
+
+// LimitForwarder bounds the forwards in flight of the forwarder, a saturated
+// limiter fails the forward with 503 and the overloaded code. the backend
+// errors of 503 and 408 are taken as dropped by the adaptive limiters.
+func LimitForwarder(forwarder Forwarder, limiter ConcurrencyLimiter) Forwarder {
+	return Forward(func(c Actor, creq any, forward func() error, rules ...validate.Rules) error {
+		return forwarder.Forward(c, creq, func() error {
+			release, ok := limiter.Acquire()
+			if !ok {
+				c.Logger().Logf(logf.Warn, "overloaded: %d forwards in flight, limit %d", limiter.InFlight(), limiter.Limit())
+				return &microerrors.Error{
+					Id:     "bird",
+					Code:   http.StatusServiceUnavailable,
+					Detail: "too many forwards in flight",
+					Status: CodeOverloaded,
+				}
+			}
+			start := time.Now()
+			err := forward()
+			release(time.Since(start), func() bool {
+				e, ok := err.(*microerrors.Error)
+				return ok && (e.Code == http.StatusServiceUnavailable || e.Code == http.StatusRequestTimeout)
+			}())
+			return err
+		}, rules...)
+	})
+}
+
+func overloaded(actor Actor, limiter ConcurrencyLimiter) {
+	actor.Logger().Logf(logf.Warn, "overloaded: %d requests in flight, limit %d", limiter.InFlight(), limiter.Limit())
+	actor.Write(http.StatusServiceUnavailable, Overloaded(errors.New("server is busy, please try again later", CodeOverloaded)))
+}
@@ -0,0 +1,142 @@
+package bird
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/dev-mockingbird/validate"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+	microerrors "go-micro.dev/v4/errors"
+)
+
+func TestFixedConcurrency(t *testing.T) {
+	limiter := FixedConcurrency(2)
+	first, ok1 := limiter.Acquire()
+	_, ok2 := limiter.Acquire()
+	if _, ok := limiter.Acquire(); !ok1 || !ok2 || ok || limiter.InFlight() != 2 {
+		t.Fatal("fixed limit not enforced", limiter.InFlight())
+	}
+	first(0, false)
+	first(0, false)
+	if limiter.InFlight() != 1 {
+		t.Fatal("release not idempotent", limiter.InFlight())
+	}
+	if _, ok := limiter.Acquire(); !ok {
+		t.Fatal("released slot not reusable")
+	}
+}
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	limiter := AIMDConcurrency(2, 0, 10, 0)
+	for i := 0; i < 20; i++ {
+		release, ok := limiter.Acquire()
+		if !ok {
+			t.Fatal("limit dropped below 1 after drops", limiter.Limit())
+		}
+		release(time.Millisecond, true)
+	}
+	if limiter.Limit() != 1 {
+		t.Fatal("limit not clamped to 1", limiter.Limit())
+	}
+	release, _ := limiter.Acquire()
+	release(time.Millisecond, false)
+	if limiter.Limit() != 2 {
+		t.Fatal("utilized limit not increased", limiter.Limit())
+	}
+
+	limiter = AIMDConcurrency(4, 2, 10, 0)
+	for i := 0; i < 20; i++ {
+		release, _ := limiter.Acquire()
+		release(time.Millisecond, true)
+	}
+	if limiter.Limit() != 2 {
+		t.Fatal("limit not clamped to min", limiter.Limit())
+	}
+
+	for name, create := range map[string]func(){
+		"fixed 0":          func() { FixedConcurrency(0) },
+		"negative min":     func() { AIMDConcurrency(2, -1, 10, 0) },
+		"max below min":    func() { GradientConcurrency(5, 5, 4) },
+		"initial over max": func() { AIMDConcurrency(11, 1, 10, 0) },
+		"zero initial":     func() { GradientConcurrency(0, 0, 10) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal(name, "invalid limiter created")
+				}
+			}()
+			create()
+		}()
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		limiter := FixedConcurrency(1)
+		var nested *httptest.ResponseRecorder
+		r.ON("/x", ConcurrencyLimit(limiter), func(actor Actor) {
+			// the request in flight saturates the limiter
+			nested = httptest.NewRecorder()
+			r.HttpHandler().ServeHTTP(nested, httptest.NewRequest(http.MethodGet, "/y", nil))
+			actor.Write(http.StatusOK, OK(nil))
+		}).Prepare(http.MethodGet)
+		r.ON("/y", ConcurrencyLimit(limiter), func(actor Actor) {
+			actor.Write(http.StatusOK, OK(nil))
+		}).Prepare(http.MethodGet)
+		rec := httptest.NewRecorder()
+		r.HttpHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x", nil))
+		var body ResponseBody
+		if err := json.Unmarshal(nested.Body.Bytes(), &body); err != nil || rec.Code != http.StatusOK ||
+			nested.Code != http.StatusServiceUnavailable || body.Code != CodeOverloaded {
+			t.Fatal(name, "saturated limiter not rejecting", rec.Code, nested.Code, body.Code)
+		}
+		if limiter.InFlight() != 0 {
+			t.Fatal(name, "slot not released", limiter.InFlight())
+		}
+	}
+}
+
+func TestLimitForwarder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pass := Forward(func(c Actor, creq any, forward func() error, rules ...validate.Rules) error {
+		return forward()
+	})
+	limiter := AIMDConcurrency(4, 1, 10, 0)
+	forwarder := LimitForwarder(pass, limiter)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	actor := GinActor(ctx, logf.New())
+
+	err := forwarder.Forward(actor, nil, func() error {
+		return &microerrors.Error{Code: http.StatusServiceUnavailable}
+	})
+	if err == nil || limiter.Limit() != 3 || limiter.InFlight() != 0 {
+		t.Fatal("backend 503 not taken as dropped", limiter.Limit(), err)
+	}
+
+	limiter = FixedConcurrency(1)
+	forwarder = LimitForwarder(pass, limiter)
+	release, _ := limiter.Acquire()
+	err = forwarder.Forward(actor, nil, func() error {
+		t.Fatal("saturated forwarder forwarded")
+		return nil
+	})
+	e, ok := err.(*microerrors.Error)
+	if !ok || e.Code != http.StatusServiceUnavailable || e.Status != CodeOverloaded {
+		t.Fatal("saturated forwarder error", err)
+	}
+	release(0, false)
+	if err := forwarder.Forward(actor, nil, func() error { return nil }); err != nil {
+		t.Fatal("released forwarder failed", err)
+	}
+}
@@ -0,0 +1,187 @@
+package bird
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dev-mockingbird/logf"
+)
+
+type corsConfig struct {
+	allowAll         bool
+	origins          map[string]bool
+	patterns         []*regexp.Regexp
+	allowOrigin      func(origin string) bool
+	methods          []string
+	headers          []string
+	exposeHeaders    []string
+	allowCredentials bool
+	maxAge           time.Duration
+}
+
+type CORSOption func(*corsConfig)
+
+// CORSAllowOrigins allows the origins, "*" allows any origin and
+// a wildcard in the host matches one or more labels, e.g. "https://*.example.com"
+func CORSAllowOrigins(origins ...string) CORSOption {
+	return func(c *corsConfig) {
+		for _, origin := range origins {
+			switch {
+			case origin == "*":
+				c.allowAll = true
+			case strings.Contains(origin, "*"):
+				pattern := strings.ReplaceAll(regexp.QuoteMeta(strings.ToLower(origin)), `\*`, `[a-z0-9.\-]+`)
+				c.patterns = append(c.patterns, regexp.MustCompile("^"+pattern+"$"))
+			default:
+				c.origins[strings.ToLower(origin)] = true
+			}
+		}
+	}
+}
+
+func CORSAllowOriginRegexp(patterns ...*regexp.Regexp) CORSOption {
+	return func(c *corsConfig) {
+		c.patterns = append(c.patterns, patterns...)
+	}
+}
+
+func CORSAllowOriginFunc(allow func(origin string) bool) CORSOption {
+	return func(c *corsConfig) {
+		c.allowOrigin = allow
+	}
+}
+
+func CORSAllowMethods(methods ...string) CORSOption {
+	return func(c *corsConfig) {
+		c.methods = methods
+	}
+}
+
+// CORSAllowHeaders sets the allowed request headers,
+// the headers asked by the preflight request are reflected if it's not set.
+func CORSAllowHeaders(headers ...string) CORSOption {
+	return func(c *corsConfig) {
+		c.headers = headers
+	}
+}
+
+func CORSExposeHeaders(headers ...string) CORSOption {
+	return func(c *corsConfig) {
+		c.exposeHeaders = headers
+	}
+}
+
+// CORSAllowCredentials allows the credentials, the origins must be configured explicitly
+// as any origin could read the credentialed responses otherwise
+func CORSAllowCredentials(allow bool) CORSOption {
+	return func(c *corsConfig) {
+		c.allowCredentials = allow
+	}
+}
+
+func CORSMaxAge(maxAge time.Duration) CORSOption {
+	return func(c *corsConfig) {
+		c.maxAge = maxAge
+	}
+}
+
+// CORS answers the cross origin requests, use it on the router or the group
+// the policy applies to. preflight requests are answered by the middleware
+// directly, routes prepared without OPTIONS get it implicitly so the preflight
+// can reach the middleware.
+//
+//	api := r.Group("/api")
+//	api.Use(bird.CORS(bird.CORSAllowOrigins("https://*.example.com"), bird.CORSAllowCredentials(true)))
+//	api.ON("/users", listUsers).Prepare(http.MethodGet)
+func CORS(opts ...CORSOption) HandleFunc {
+	c := corsConfig{
+		origins: make(map[string]bool),
+		methods: []string{
+			http.MethodGet,
+			http.MethodHead,
+			http.MethodPost,
+			http.MethodPut,
+			http.MethodPatch,
+			http.MethodDelete,
+		},
+		exposeHeaders: []string{"Request-Id"},
+	}
+	for _, apply := range opts {
+		apply(&c)
+	}
+	if len(c.origins) == 0 && len(c.patterns) == 0 && c.allowOrigin == nil {
+		c.allowAll = true
+	}
+	if c.allowAll && c.allowCredentials {
+		panic("bird: CORS with credentials requires the allowed origins, not any origin")
+	}
+	methods := strings.Join(c.methods, ", ")
+	headers := strings.Join(c.headers, ", ")
+	exposeHeaders := strings.Join(c.exposeHeaders, ", ")
+	return func(actor Actor) {
+		req := actor.GetRequest()
+		origin := req.Header.Get("Origin")
+		header := actor.GetResponseWriter().Header()
+		preflight := req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != ""
+		if origin == "" {
+			actor.Next()
+			return
+		}
+		header.Add("Vary", "Origin")
+		if !c.allowed(origin) {
+			if preflight {
+				actor.Logger().Logf(logf.Trace, "reject preflight from origin: %s", origin)
+				abortWithStatus(actor, http.StatusForbidden)
+				return
+			}
+			actor.Next()
+			return
+		}
+		if c.allowAll {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if c.allowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			actor.Next()
+			return
+		}
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", methods)
+		if headers != "" {
+			header.Set("Access-Control-Allow-Headers", headers)
+		} else if h := req.Header.Get("Access-Control-Request-Headers"); h != "" {
+			header.Set("Access-Control-Allow-Headers", h)
+		}
+		if c.maxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(c.maxAge.Seconds())))
+		}
+		abortWithStatus(actor, http.StatusNoContent)
+	}
+}
+
+func (c corsConfig) allowed(origin string) bool {
+	if c.allowAll {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if c.origins[origin] {
+		return true
+	}
+	for _, pattern := range c.patterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return c.allowOrigin != nil && c.allowOrigin(origin)
+}
@@ -0,0 +1,70 @@
+package bird
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		r.Use(CORS(CORSAllowOrigins("https://*.example.com"), CORSAllowCredentials(true), CORSMaxAge(10*time.Minute)))
+		r.ON("/x", func(actor Actor) {
+			actor.Write(http.StatusOK, OK(nil))
+		}).Prepare(http.MethodGet)
+		h := r.HttpHandler()
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodOptions, "/x", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+			rec.Header().Get("Access-Control-Allow-Credentials") != "true" || rec.Header().Get("Access-Control-Max-Age") != "600" {
+			t.Fatal(name, "preflight error", rec.Code, rec.Header())
+		}
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodOptions, "/x", nil)
+		req.Header.Set("Origin", "https://evil.example")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Fatal(name, "preflight of disallowed origin answered", rec.Code)
+		}
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/x", nil)
+		req.Header.Set("Origin", "https://evil.example")
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "" || rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Fatal(name, "disallowed origin allowed", rec.Header())
+		}
+	}
+}
+
+func TestCORSCredentialsRequireOrigins(t *testing.T) {
+	for name, opts := range map[string][]CORSOption{
+		"no origins": {CORSAllowCredentials(true)},
+		"any origin": {CORSAllowOrigins("*"), CORSAllowCredentials(true)},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal(name, "credentials allowed for any origin")
+				}
+			}()
+			CORS(opts...)
+		}()
+	}
+	CORS(CORSAllowOrigins("*"))
+}
@@ -0,0 +1,221 @@
+package bird
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dev-mockingbird/errors"
+	"github.com/dev-mockingbird/logf"
+)
+
+const (
+	DefaultCSRFCookieName = "_csrf"
+	DefaultCSRFHeaderName = "X-CSRF-Token"
+	DefaultCSRFFormField  = "csrf_token"
+	// the key under which the token is exposed through Actor.Get
+	CSRFTokenKey = "bird.csrf-token"
+)
+
+type csrfConfig struct {
+	cookieName  string
+	cookiePath  string
+	domain      string
+	headerName  string
+	formField   string
+	secret      []byte
+	maxAge      time.Duration
+	secure      bool
+	sameSite    http.SameSite
+	tokenLength int
+	exempt      func(Actor) bool
+}
+
+type CSRFOption func(*csrfConfig)
+
+func CSRFCookie(name, path, domain string) CSRFOption {
+	return func(c *csrfConfig) {
+		c.cookieName = name
+		c.cookiePath = path
+		c.domain = domain
+	}
+}
+
+func CSRFHeader(name string) CSRFOption {
+	return func(c *csrfConfig) {
+		c.headerName = name
+	}
+}
+
+func CSRFFormField(name string) CSRFOption {
+	return func(c *csrfConfig) {
+		c.formField = name
+	}
+}
+
+// CSRFSecret signs the issued token with HMAC-SHA256, so the cookie
+// can't be forged by a sibling domain that is able to write cookies.
+func CSRFSecret(secret []byte) CSRFOption {
+	return func(c *csrfConfig) {
+		c.secret = secret
+	}
+}
+
+func CSRFMaxAge(maxAge time.Duration) CSRFOption {
+	return func(c *csrfConfig) {
+		c.maxAge = maxAge
+	}
+}
+
+func CSRFSecure(secure bool) CSRFOption {
+	return func(c *csrfConfig) {
+		c.secure = secure
+	}
+}
+
+func CSRFSameSite(sameSite http.SameSite) CSRFOption {
+	return func(c *csrfConfig) {
+		c.sameSite = sameSite
+	}
+}
+
+// CSRFExempt skips the token check for the requests the func reports true,
+// the token is still issued.
+func CSRFExempt(exempt func(Actor) bool) CSRFOption {
+	return func(c *csrfConfig) {
+		c.exempt = exempt
+	}
+}
+
+// CSRF protects the routes with the double-submit-cookie pattern.
+// a token is issued in a cookie and exposed by Actor.Get(CSRFTokenKey),
+// unsafe requests must echo it back through the header or the field of the url encoded form.
+//
+//	r.Use(bird.CSRF(bird.CSRFSecret(secret)))
+//	r.ON("/form", func(actor bird.Actor) {
+//	    token, _ := actor.Get(bird.CSRFTokenKey)
+//	    ...
+//	}).Prepare()
+func CSRF(opts ...CSRFOption) HandleFunc {
+	c := csrfConfig{
+		cookieName:  DefaultCSRFCookieName,
+		cookiePath:  "/",
+		headerName:  DefaultCSRFHeaderName,
+		formField:   DefaultCSRFFormField,
+		maxAge:      12 * time.Hour,
+		sameSite:    http.SameSiteLaxMode,
+		tokenLength: 32,
+	}
+	for _, apply := range opts {
+		apply(&c)
+	}
+	return func(actor Actor) {
+		req := actor.GetRequest()
+		token := ""
+		if cookie, err := req.Cookie(c.cookieName); err == nil && c.verify(cookie.Value) {
+			token = cookie.Value
+		}
+		issued := token == ""
+		if issued {
+			var err error
+			if token, err = c.generate(); err != nil {
+				actor.Logger().Logf(logf.Error, "generate csrf token: %s", err.Error())
+				actor.Write(http.StatusInternalServerError, UnknownError(err, "internal server error, please try again"))
+				return
+			}
+			http.SetCookie(actor.GetResponseWriter(), &http.Cookie{
+				Name:     c.cookieName,
+				Value:    token,
+				Path:     c.cookiePath,
+				Domain:   c.domain,
+				MaxAge:   int(c.maxAge.Seconds()),
+				Secure:   c.secure,
+				HttpOnly: true,
+				SameSite: c.sameSite,
+			})
+		}
+		actor.Set(CSRFTokenKey, token)
+		if isSafeMethod(req.Method) || c.exempt != nil && c.exempt(actor) {
+			actor.Next()
+			return
+		}
+		if issued {
+			csrfReject(actor, "csrf token cookie missing")
+			return
+		}
+		submitted := req.Header.Get(c.headerName)
+		// only the url encoded forms are read, the multipart ones are left to the limits of Uploads
+		if submitted == "" && mediaType(req.Header.Get("Content-Type")) == "application/x-www-form-urlencoded" {
+			submitted = req.PostFormValue(c.formField)
+		}
+		if submitted == "" {
+			csrfReject(actor, "csrf token missing")
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+			csrfReject(actor, "csrf token mismatch")
+			return
+		}
+		actor.Next()
+	}
+}
+
+// CSRFToken returns the token issued by the CSRF middleware for the request
+func CSRFToken(actor Actor) string {
+	if token, ok := actor.Get(CSRFTokenKey); ok {
+		if s, ok := token.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+func csrfReject(actor Actor, msg string) {
+	actor.Logger().Logf(logf.Trace, "reject request: %s", msg)
+	actor.Write(http.StatusForbidden, CSRFInvalid(errors.New(msg, CodeCSRFInvalid)))
+}
+
+func (c csrfConfig) generate() (string, error) {
+	b := make([]byte, c.tokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	if len(c.secret) == 0 {
+		return token, nil
+	}
+	return token + "." + c.sign(token), nil
+}
+
+func (c csrfConfig) verify(token string) bool {
+	if token == "" {
+		return false
+	}
+	if len(c.secret) == 0 {
+		return !strings.Contains(token, ".")
+	}
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return false
+	}
+	return hmac.Equal([]byte(token[i+1:]), []byte(c.sign(token[:i])))
+}
+
+func (c csrfConfig) sign(token string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func isSafeMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
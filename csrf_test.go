@@ -0,0 +1,103 @@
+package bird
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+func TestCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		var multipartParsed bool
+		r.Use(CSRF(CSRFSecret([]byte("secret"))))
+		r.ON("/form", func(actor Actor) {
+			multipartParsed = actor.GetRequest().MultipartForm != nil
+			actor.Write(http.StatusOK, OK(CSRFToken(actor)))
+		}).Prepare()
+		h := r.HttpHandler()
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/form", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s safe method rejected: %d", name, rec.Code)
+		}
+		cookies := rec.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != DefaultCSRFCookieName {
+			t.Fatal(name, "csrf cookie not issued")
+		}
+		var body struct {
+			Code string `json:"code"`
+			Data string `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Data != cookies[0].Value {
+			t.Fatal(name, "csrf token not exposed on actor")
+		}
+
+		rec = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/form", nil)
+		req.AddCookie(cookies[0])
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("%s missing token accepted: %d", name, rec.Code)
+		}
+		var rejected ResponseBody
+		if err := json.Unmarshal(rec.Body.Bytes(), &rejected); err != nil || rejected.Code != CodeCSRFInvalid {
+			t.Fatal(name, "csrf rejection code error")
+		}
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/form", nil)
+		req.AddCookie(cookies[0])
+		req.Header.Set(DefaultCSRFHeaderName, cookies[0].Value)
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s valid token rejected: %d", name, rec.Code)
+		}
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/form", strings.NewReader(url.Values{DefaultCSRFFormField: {cookies[0].Value}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(cookies[0])
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s valid form token rejected: %d", name, rec.Code)
+		}
+
+		// the multipart body isn't parsed for the token
+		var form bytes.Buffer
+		w := multipart.NewWriter(&form)
+		w.WriteField(DefaultCSRFFormField, cookies[0].Value)
+		w.Close()
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/form", &form)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		req.AddCookie(cookies[0])
+		multipartParsed = false
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden || multipartParsed || req.MultipartForm != nil {
+			t.Fatalf("%s multipart body parsed for the token: %d", name, rec.Code)
+		}
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/form", nil)
+		req.AddCookie(&http.Cookie{Name: DefaultCSRFCookieName, Value: "forged"})
+		req.Header.Set(DefaultCSRFHeaderName, "forged")
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("%s forged token accepted: %d", name, rec.Code)
+		}
+	}
+}
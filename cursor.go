@@ -0,0 +1,149 @@
+package bird
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	birderrors "github.com/dev-mockingbird/errors"
+)
+
+// the errors of the cursors are tagged invalid-cursor
+var (
+	ErrInvalidCursor = birderrors.New("invalid cursor", CodeInvalidCursor)
+	ErrCursorExpired = birderrors.New("cursor expired", CodeInvalidCursor)
+)
+
+const cursorVersion = 1
+
+// CursorKey is a key of the cursor tokens, the id is in the token so that
+// the tokens of the rotated keys can be decoded
+type CursorKey struct {
+	Id     string
+	Secret []byte
+}
+
+type cursorKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+type CursorOption func(*CursorCodec)
+
+// CursorTTL expires the tokens after the ttl, 24 hours by default, 0 never expires
+func CursorTTL(ttl time.Duration) CursorOption {
+	return func(c *CursorCodec) {
+		c.ttl = ttl
+	}
+}
+
+// CursorCodec encodes the cursors of the keyset pagination into opaque tokens, the cursors
+// are serialized as JSON and encrypted with AES-GCM, so that the clients can neither read
+// nor tamper with them
+type CursorCodec struct {
+	keys []cursorKey
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+type cursorPayload struct {
+	Expires int64           `json:"e,omitempty"`
+	Cursor  json.RawMessage `json:"c"`
+}
+
+// NewCursorCodec encodes the tokens with the first key and decodes them with any of the keys,
+// to rotate the keys, prepend the new key and drop the old one once its tokens expire
+//
+//	codec, err := bird.NewCursorCodec([]bird.CursorKey{{Id: "2023-02", Secret: newSecret}, {Id: "2023-01", Secret: oldSecret}})
+//	next, err := codec.Encode(orderCursor{CreatedAt: last.CreatedAt, Id: last.Id})
+//	actor.Write(http.StatusOK, bird.Paged(orders, next, -1))
+func NewCursorCodec(keys []CursorKey, opts ...CursorOption) (*CursorCodec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("a cursor key is required")
+	}
+	c := &CursorCodec{ttl: 24 * time.Hour, now: time.Now}
+	for _, key := range keys {
+		if len(key.Id) == 0 || len(key.Id) > 255 || len(key.Secret) == 0 {
+			return nil, errors.New("cursor key requires an id of 1 to 255 bytes and a secret")
+		}
+		// the secret of any length is derived into an AES-256 key
+		secret := sha256.Sum256(key.Secret)
+		block, err := aes.NewCipher(secret[:])
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		c.keys = append(c.keys, cursorKey{id: key.Id, aead: aead})
+	}
+	for _, apply := range opts {
+		apply(c)
+	}
+	return c, nil
+}
+
+// Encode encodes the cursor into a token, the token is version, key id and sealed payload
+func (c *CursorCodec) Encode(cursor any) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	payload := cursorPayload{Cursor: data}
+	if c.ttl > 0 {
+		payload.Expires = c.now().Add(c.ttl).Unix()
+	}
+	plain, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	key := c.keys[0]
+	header := append([]byte{cursorVersion, byte(len(key.id))}, key.id...)
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	token := append(append(header, nonce...), key.aead.Seal(nil, nonce, plain, header)...)
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// Decode decodes the token into the cursor, the tampered, unknown and expired tokens fail
+// with the errors tagged invalid-cursor
+func (c *CursorCodec) Decode(token string, cursor any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) < 2 || raw[0] != cursorVersion || len(raw) < 2+int(raw[1]) {
+		return ErrInvalidCursor
+	}
+	header, id := raw[:2+int(raw[1])], string(raw[2:2+int(raw[1])])
+	for _, key := range c.keys {
+		if key.id != id {
+			continue
+		}
+		sealed := raw[len(header):]
+		if len(sealed) < key.aead.NonceSize() {
+			return ErrInvalidCursor
+		}
+		plain, err := key.aead.Open(nil, sealed[:key.aead.NonceSize()], sealed[key.aead.NonceSize():], header)
+		if err != nil {
+			return ErrInvalidCursor
+		}
+		var payload cursorPayload
+		if err := json.Unmarshal(plain, &payload); err != nil {
+			return ErrInvalidCursor
+		}
+		if payload.Expires > 0 && c.now().Unix() > payload.Expires {
+			return ErrCursorExpired
+		}
+		if err := json.Unmarshal(payload.Cursor, cursor); err != nil {
+			return ErrInvalidCursor
+		}
+		return nil
+	}
+	return ErrInvalidCursor
+}
@@ -0,0 +1,46 @@
+package bird
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursorCodec(t *testing.T) {
+	type orderCursor struct {
+		Created int64  `json:"created"`
+		Id      string `json:"id"`
+	}
+	old, err := NewCursorCodec([]CursorKey{{Id: "1", Secret: []byte("old")}}, CursorTTL(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := old.Encode(orderCursor{Created: 42, Id: "order-7"})
+	if err != nil || strings.Contains(token, "order") {
+		t.Fatal("cursor not opaque", token, err)
+	}
+	rotated, _ := NewCursorCodec([]CursorKey{{Id: "2", Secret: []byte("new")}, {Id: "1", Secret: []byte("old")}})
+	var got orderCursor
+	if err := rotated.Decode(token, &got); err != nil || got != (orderCursor{Created: 42, Id: "order-7"}) {
+		t.Fatal("rotated key can't decode", got, err)
+	}
+	dropped, _ := NewCursorCodec([]CursorKey{{Id: "2", Secret: []byte("new")}})
+	if err := dropped.Decode(token, &got); ErrorOccurred(err, CodeUnkownError).Code != CodeInvalidCursor {
+		t.Fatal("dropped key decodes", err)
+	}
+
+	raw, _ := base64.RawURLEncoding.DecodeString(token)
+	raw[len(raw)-1] ^= 1
+	if err := old.Decode(base64.RawURLEncoding.EncodeToString(raw), &got); ErrorOccurred(err, CodeUnkownError).Code != CodeInvalidCursor {
+		t.Fatal("tampered cursor decodes", err)
+	}
+	if err := old.Decode("garbage", &got); ErrorOccurred(err, CodeUnkownError).Code != CodeInvalidCursor {
+		t.Fatal("garbage cursor decodes", err)
+	}
+
+	old.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if err := old.Decode(token, &got); err == nil || InvalidCursor(err).Data != Msg("cursor expired") {
+		t.Fatal("expired cursor decodes", err)
+	}
+}
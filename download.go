@@ -0,0 +1,210 @@
+package bird
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serveContent serves the content with the Range, If-Range and the conditional
+// requests, the content type is detected from the name or the content
+func serveContent(actor Actor, name string, modTime time.Time, etag string, content io.ReadSeeker) {
+	w := actor.GetResponseWriter()
+	w.Header().Set("Request-Id", actor.RequestId())
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	http.ServeContent(w, actor.GetRequest(), name, modTime, content)
+	abort(actor)
+}
+
+// fileETag is the etag of the size and the modification time
+func fileETag(info fs.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+}
+
+// serveFile implements Actor.ServeFile, nothing is written if the file can't be served
+func serveFile(actor Actor, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory: %w", file, fs.ErrNotExist)
+	}
+	serveContent(actor, filepath.Base(file), info.ModTime(), fileETag(info), f)
+	return nil
+}
+
+// attachment implements Actor.Attachment, the ranges are served only if the content is seekable
+func attachment(actor Actor, content io.Reader, name string) error {
+	w := actor.GetResponseWriter()
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		var modTime time.Time
+		var etag string
+		if f, ok := content.(interface{ Stat() (fs.FileInfo, error) }); ok {
+			if info, err := f.Stat(); err == nil {
+				modTime, etag = info.ModTime(), fileETag(info)
+			}
+		}
+		serveContent(actor, name, modTime, etag, seeker)
+		return nil
+	}
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return actor.Stream(http.StatusOK, contentType, func(w io.Writer) error {
+		_, err := io.Copy(w, content)
+		return err
+	})
+}
+
+// staticRedirect redirects the prefix of Router.Static to the prefix with the trailing slash,
+// so that the relative links of the index resolve in the directory
+func staticRedirect(prefix string) HandleFunc {
+	return func(actor Actor) {
+		req := actor.GetRequest()
+		target := prefix + "/"
+		if req.URL.RawQuery != "" {
+			target += "?" + req.URL.RawQuery
+		}
+		http.Redirect(actor.GetResponseWriter(), req, target, http.StatusMovedPermanently)
+		abort(actor)
+	}
+}
+
+type staticConfig struct {
+	index    string
+	fallback string
+	maxAge   time.Duration
+}
+
+type StaticOption func(*staticConfig)
+
+// StaticIndex is the file served for the directories, index.html by default
+func StaticIndex(name string) StaticOption {
+	return func(c *staticConfig) {
+		c.index = name
+	}
+}
+
+// StaticSPA serves the fallback file, e.g. index.html, for the missing paths
+// without extension, so that the routes of a single page application work
+func StaticSPA(fallback string) StaticOption {
+	return func(c *staticConfig) {
+		c.fallback = fallback
+	}
+}
+
+// StaticMaxAge sets the max age of Cache-Control
+func StaticMaxAge(maxAge time.Duration) StaticOption {
+	return func(c *staticConfig) {
+		c.maxAge = maxAge
+	}
+}
+
+// staticFiles serves the files of the file system for Router.Static
+type staticFiles struct {
+	fsys fs.FS
+	staticConfig
+	// the etags of the files without modification time, e.g. of embed.FS
+	etags sync.Map
+}
+
+func newStaticFiles(fsys fs.FS, opts ...StaticOption) *staticFiles {
+	s := &staticFiles{fsys: fsys, staticConfig: staticConfig{index: "index.html"}}
+	for _, apply := range opts {
+		apply(&s.staticConfig)
+	}
+	return s
+}
+
+// handler serves the file of the path returned by the param
+func (s *staticFiles) handler(param func(Actor) string) HandleFunc {
+	return func(actor Actor) {
+		name := strings.TrimPrefix(path.Clean("/"+param(actor)), "/")
+		if name == "" {
+			name = "."
+		}
+		err := s.serve(actor, name)
+		if err != nil && s.fallback != "" && path.Ext(name) == "" && errors.Is(err, fs.ErrNotExist) {
+			err = s.serve(actor, s.fallback)
+		}
+		if err == nil {
+			return
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			actor.Write(http.StatusNotFound, NotFound(err, "file not found"))
+			return
+		}
+		actor.Write(http.StatusInternalServerError, UnknownError(err))
+	}
+}
+
+func (s *staticFiles) serve(actor Actor, name string) error {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		if s.index == "" {
+			return fs.ErrNotExist
+		}
+		return s.serve(actor, path.Join(name, s.index))
+	}
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		return fmt.Errorf("%s isn't seekable", name)
+	}
+	etag := fileETag(info)
+	if info.ModTime().IsZero() {
+		if etag, err = s.contentETag(name, content); err != nil {
+			return err
+		}
+	}
+	if s.maxAge > 0 {
+		actor.GetResponseWriter().Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(s.maxAge.Seconds())))
+	}
+	serveContent(actor, info.Name(), info.ModTime(), etag, content)
+	return nil
+}
+
+// contentETag hashes the content once, the files without modification time don't change
+func (s *staticFiles) contentETag(name string, content io.ReadSeeker) (string, error) {
+	if etag, ok := s.etags.Load(name); ok {
+		return etag.(string), nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	s.etags.Store(name, etag)
+	return etag, nil
+}
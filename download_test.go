@@ -0,0 +1,161 @@
+package bird
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+func TestStatic(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":  {Data: []byte("<html>app</html>")},
+		"js/app.js":   {Data: []byte("console.log('hello world')")},
+		"js/page.css": {Data: []byte("body {}")},
+	}
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		r.Static("/app", fsys, StaticSPA("index.html"))
+		serve := func(path string, header ...string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			for i := 0; i+1 < len(header); i += 2 {
+				req.Header.Set(header[i], header[i+1])
+			}
+			rec := httptest.NewRecorder()
+			r.HttpHandler().ServeHTTP(rec, req)
+			return rec
+		}
+		rec := serve("/app/js/app.js", "Range", "bytes=0-6")
+		if rec.Code != http.StatusPartialContent || rec.Body.String() != "console" {
+			t.Fatal(name, "range not served", rec.Code, rec.Body.String())
+		}
+		etag := rec.Header().Get("ETag")
+		if rec := serve("/app/js/app.js", "If-None-Match", etag); etag == "" || rec.Code != http.StatusNotModified {
+			t.Fatal(name, "conditional request not served", rec.Code)
+		}
+		if rec := serve("/app/orders/1"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "app") {
+			t.Fatal(name, "spa fallback not served", rec.Code)
+		}
+		if rec := serve("/app/js/missing.js"); rec.Code != http.StatusNotFound {
+			t.Fatal(name, "missing file served", rec.Code)
+		}
+		if rec := serve("/app?v=1"); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/app/?v=1" {
+			t.Fatal(name, "prefix not redirected", rec.Code, rec.Header().Get("Location"))
+		}
+		if rec := serve("/app/"); rec.Code != http.StatusOK || rec.Body.String() != "<html>app</html>" {
+			t.Fatal(name, "index not served", rec.Code)
+		}
+	}
+}
+
+func TestServeFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(file, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		r.ON("/report", func(actor Actor) {
+			if err := actor.ServeFile(file); err != nil {
+				actor.Write(http.StatusInternalServerError, UnknownError(err))
+			}
+		}).Prepare(http.MethodGet)
+		r.ON("/missing", func(actor Actor) {
+			if err := actor.ServeFile(file + ".missing"); errors.Is(err, fs.ErrNotExist) {
+				actor.Write(http.StatusNotFound, NotFound(err))
+			}
+		}).Prepare(http.MethodGet)
+		serve := func(path string, header ...string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			for i := 0; i+1 < len(header); i += 2 {
+				req.Header.Set(header[i], header[i+1])
+			}
+			rec := httptest.NewRecorder()
+			r.HttpHandler().ServeHTTP(rec, req)
+			return rec
+		}
+
+		rec := serve("/report")
+		etag, modified := rec.Header().Get("ETag"), rec.Header().Get("Last-Modified")
+		if rec.Code != http.StatusOK || rec.Body.String() != "0123456789" || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") ||
+			rec.Header().Get("Accept-Ranges") != "bytes" || etag == "" || modified == "" {
+			t.Fatal(name, "file not served", rec.Code, rec.Header(), rec.Body.String())
+		}
+		if rec := serve("/report", "Range", "bytes=2-4"); rec.Code != http.StatusPartialContent || rec.Body.String() != "234" ||
+			rec.Header().Get("Content-Range") != "bytes 2-4/10" {
+			t.Fatal(name, "range not served", rec.Code, rec.Body.String())
+		}
+		if rec := serve("/report", "Range", "bytes=2-4", "If-Range", etag); rec.Code != http.StatusPartialContent || rec.Body.String() != "234" {
+			t.Fatal(name, "range of the current etag not served", rec.Code)
+		}
+		if rec := serve("/report", "Range", "bytes=2-4", "If-Range", `"stale"`); rec.Code != http.StatusOK || rec.Body.String() != "0123456789" {
+			t.Fatal(name, "range of a stale etag served", rec.Code, rec.Body.String())
+		}
+		if rec := serve("/report", "If-None-Match", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Fatal(name, "etag not matched", rec.Code)
+		}
+		if rec := serve("/report", "If-Modified-Since", modified); rec.Code != http.StatusNotModified {
+			t.Fatal(name, "modification time not matched", rec.Code)
+		}
+		if rec := serve("/report", "Range", "bytes=20-30"); rec.Code != http.StatusRequestedRangeNotSatisfiable {
+			t.Fatal(name, "unsatisfiable range served", rec.Code)
+		}
+		if rec := serve("/missing"); rec.Code != http.StatusNotFound || rec.Header().Get("ETag") != "" {
+			t.Fatal(name, "missing file written", rec.Code, rec.Header())
+		}
+	}
+}
+
+func TestAttachment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		r.ON("/seekable", func(actor Actor) {
+			actor.Attachment(strings.NewReader("0123456789"), "my report.txt")
+		}).Prepare(http.MethodGet)
+		r.ON("/stream", func(actor Actor) {
+			actor.Attachment(io.MultiReader(strings.NewReader("01234"), strings.NewReader("56789")), "résumé.pdf")
+		}).Prepare(http.MethodGet)
+		serve := func(path string, header ...string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			for i := 0; i+1 < len(header); i += 2 {
+				req.Header.Set(header[i], header[i+1])
+			}
+			rec := httptest.NewRecorder()
+			r.HttpHandler().ServeHTTP(rec, req)
+			return rec
+		}
+
+		rec := serve("/seekable", "Range", "bytes=0-3")
+		if rec.Code != http.StatusPartialContent || rec.Body.String() != "0123" || rec.Header().Get("Content-Disposition") != `attachment; filename="my report.txt"` {
+			t.Fatal(name, "seekable attachment", rec.Code, rec.Header(), rec.Body.String())
+		}
+		// the ranges of the reader that can't seek are ignored
+		rec = serve("/stream", "Range", "bytes=0-3")
+		if rec.Code != http.StatusOK || rec.Body.String() != "0123456789" || rec.Header().Get("Content-Type") != "application/pdf" ||
+			rec.Header().Get("Accept-Ranges") != "" {
+			t.Fatal(name, "stream attachment", rec.Code, rec.Header(), rec.Body.String())
+		}
+		if disposition := rec.Header().Get("Content-Disposition"); disposition != `attachment; filename*=utf-8''r%C3%A9sum%C3%A9.pdf` {
+			t.Fatal(name, "non-ascii filename not encoded", disposition)
+		}
+	}
+}
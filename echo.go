@@ -2,6 +2,8 @@ package bird
 
 import (
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
 	"strings"
 
@@ -11,11 +13,16 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// echoRoutesKey is the key of the routes of the router, the middlewares set it for routePattern
+const echoRoutesKey = "bird.routes"
+
 type echoEntry struct {
-	g      *echo.Group
-	logger logf.Logger
-	path   string
-	acts   []HandleFunc
+	g       *echo.Group
+	logger  logf.Logger
+	base    string
+	path    string
+	acts    []HandleFunc
+	methods *routeMethods
 }
 
 type EchoContextGetter interface {
@@ -27,6 +34,7 @@ func constructEchoActor(ctx echo.Context, logger logf.Logger, next echo.HandlerF
 	if reqId == "" {
 		reqId = uuid.NewString()
 		ctx.Request().Header.Add("Request-Id", reqId)
+		ctx.Set(requestIdGeneratedKey, true)
 	}
 	method := strings.ToUpper(ctx.Request().Method)
 	path := ctx.Request().URL.Path
@@ -35,17 +43,47 @@ func constructEchoActor(ctx echo.Context, logger logf.Logger, next echo.HandlerF
 }
 
 func (entry echoEntry) Prepare(methods ...string) {
-	for _, act := range entry.acts {
-		h := func(ctx echo.Context) error {
-			act(constructEchoActor(ctx, entry.logger, nil))
+	h := entry.handler()
+	if len(methods) == 0 {
+		entry.methods.register(joinPaths(entry.base, entry.path))
+		entry.g.Any(entry.path, h)
+		return
+	}
+	entry.g.Match(methods, entry.path, h)
+	entry.prepareOptions(methods...)
+}
+
+// handler chains the acts, every act continues to the next one by Actor.Next
+func (entry echoEntry) handler() echo.HandlerFunc {
+	next := func(ctx echo.Context) error {
+		return nil
+	}
+	for i := len(entry.acts) - 1; i >= 0; i-- {
+		act, n := entry.acts[i], next
+		next = func(ctx echo.Context) error {
+			actor := constructEchoActor(ctx, entry.logger, n)
+			act(actor)
+			closeEventStreams(actor)
 			return nil
 		}
-		if len(methods) == 0 {
-			entry.g.Any(entry.path, h)
-			return
-		}
-		entry.g.Match(methods, entry.path, h)
 	}
+	return next
+}
+
+// prepareOptions queues the implicit OPTIONS route, so that the group
+// middlewares (CORS for example) can see the preflight requests.
+func (entry echoEntry) prepareOptions(methods ...string) {
+	path := joinPaths(entry.base, entry.path)
+	entry.methods.register(path, methods...)
+	entry.methods.implicitOptions(path, func() {
+		entry.g.OPTIONS(entry.path, func(ctx echo.Context) error {
+			if ctx.Response().Committed {
+				return nil
+			}
+			ctx.Response().Header().Set("Allow", entry.methods.allow(path))
+			return ctx.NoContent(http.StatusNoContent)
+		})
+	})
 }
 
 type echoActor struct {
@@ -56,21 +94,25 @@ type echoActor struct {
 }
 
 type echoRouter struct {
-	logger logf.Logger
-	e      *echo.Echo
-	g      *echo.Group
+	logger  logf.Logger
+	e       *echo.Echo
+	g       *echo.Group
+	base    string
+	methods *routeMethods
 }
 
 var _ Router = &echoRouter{}
 
 func EchoRouter(e *echo.Echo, logger logf.Logger) Router {
-	return &echoRouter{logger: logger, e: e, g: e.Group("")}
+	return &echoRouter{logger: logger, e: e, g: e.Group(""), methods: newRouteMethods()}
 }
 
 func (r echoRouter) Use(acts ...HandleFunc) {
 	for _, act := range acts {
+		act := act
 		r.g.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
 			return func(ctx echo.Context) error {
+				ctx.Set(echoRoutesKey, r.methods)
 				actor := constructEchoActor(ctx, r.logger, next)
 				act(actor)
 				return nil
@@ -81,22 +123,41 @@ func (r echoRouter) Use(acts ...HandleFunc) {
 
 func (r echoRouter) ON(path string, acts ...HandleFunc) Entry {
 	return echoEntry{
-		path:   path,
-		logger: r.logger,
-		g:      r.g,
-		acts:   acts,
+		path:    path,
+		logger:  r.logger,
+		g:       r.g,
+		base:    r.base,
+		acts:    acts,
+		methods: r.methods,
 	}
 }
 
 func (r echoRouter) Group(base string) Router {
 	return echoRouter{
-		logger: r.logger.Prefix(base + ": "),
-		e:      r.e,
-		g:      r.e.Group(base),
+		logger:  r.logger.Prefix(base + ": "),
+		e:       r.e,
+		g:       r.g.Group(base),
+		base:    joinPaths(r.base, base),
+		methods: r.methods,
+	}
+}
+
+func (r echoRouter) WS(path string, handle func(conn Conn), opts ...WSOption) {
+	r.ON(path, WebSocket(handle, opts...)).Prepare(http.MethodGet)
+}
+
+func (r echoRouter) Static(prefix string, fsys fs.FS, opts ...StaticOption) {
+	files := newStaticFiles(fsys, opts...)
+	r.ON(joinPaths(prefix, "/*"), files.handler(func(actor Actor) string {
+		return actor.Param("*")
+	})).Prepare(http.MethodGet, http.MethodHead)
+	if dir := strings.TrimSuffix(joinPaths(r.base, prefix), "/"); dir != "" {
+		r.ON(strings.TrimSuffix(prefix, "/"), staticRedirect(dir)).Prepare(http.MethodGet, http.MethodHead)
 	}
 }
 
 func (g echoRouter) HttpHandler() http.Handler {
+	g.methods.prepareImplicit()
 	return g.e
 }
 
@@ -124,13 +185,26 @@ func (g echoActor) Validate(data any, rules ...validate.Rules) error {
 	return nil
 }
 
+func (g echoActor) Bind(obj any) error {
+	if err := bind(g, obj); err != nil {
+		g.logger.Logf(logf.Error, "bind object: %s", err.Error())
+		return err
+	}
+	g.logger.Logf(logf.Trace, "get input object: %#v", obj)
+	return nil
+}
+
 func (g echoActor) Query(key string) string {
 	return g.Context.QueryParam(key)
 }
 
+// Next calls the next handler, the errors of the echo handlers, e.g. the 404 of
+// the paths matching no route, are written by the echo error handler
 func (g echoActor) Next() {
 	if g.next != nil {
-		g.next(g.Context)
+		if err := g.next(g.Context); err != nil && !g.Context.Response().Committed {
+			g.Context.Error(err)
+		}
 	}
 }
 
@@ -154,11 +228,37 @@ func (g echoActor) Get(key string) (any, bool) {
 	return ret, true
 }
 
+func (g echoActor) File(name string) (*UploadedFile, error) {
+	return formFile(g, name)
+}
+
+func (g echoActor) Files(name string) ([]*UploadedFile, error) {
+	return formFiles(g, name)
+}
+
 func (g echoActor) Write(statusCode int, data any) error {
+	recordResponseCode(g, data)
+	writeLinks(g, data)
 	g.Context.Request().Header.Add("Request-Id", g.RequestId())
 	return g.Context.JSON(statusCode, data)
 }
 
+func (g echoActor) Stream(statusCode int, contentType string, write func(w io.Writer) error) error {
+	return stream(g, statusCode, contentType, write)
+}
+
+func (g echoActor) SSE() *EventStream {
+	return newEventStream(g)
+}
+
+func (g echoActor) ServeFile(path string) error {
+	return serveFile(g, path)
+}
+
+func (g echoActor) Attachment(content io.Reader, name string) error {
+	return attachment(g, content, name)
+}
+
 func (g echoActor) GetRequest() *http.Request {
 	return g.Context.Request()
 }
@@ -171,6 +271,8 @@ func (g echoActor) GetContext() echo.Context {
 	return g.Context
 }
 
+// GetResponseWriter returns the echo response, so that the status and the size
+// written through it are tracked by echo
 func (g echoActor) GetResponseWriter() http.ResponseWriter {
-	return g.Context.Response().Writer
+	return g.Context.Response()
 }
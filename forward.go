@@ -24,7 +24,7 @@ func GetForwarder() Forwarder {
 	return Forward(func(c Actor, creq any, forward func() error, rules ...validate.Rules) error {
 		if err := c.Bind(creq); err != nil {
 			msg := fmt.Sprintf("can't parse request: %s", err.Error())
-			c.Write(http.StatusBadRequest, InvalidArguments(err, msg))
+			c.Write(BindErrorStatus(err), InvalidArguments(err, msg))
 			c.Logger().Logf(logf.Trace, msg)
 			return err
 		}
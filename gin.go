@@ -2,6 +2,8 @@ package bird
 
 import (
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
 	"strings"
 	"time"
@@ -12,10 +14,11 @@ import (
 )
 
 type ginEntry struct {
-	g      *gin.RouterGroup
-	logger logf.Logger
-	path   string
-	acts   []HandleFunc
+	g       *gin.RouterGroup
+	logger  logf.Logger
+	path    string
+	acts    []HandleFunc
+	methods *routeMethods
 }
 
 type GinContextGetter interface {
@@ -27,6 +30,7 @@ func constructGinActor(ctx *gin.Context, logger logf.Logger) Actor {
 	if reqId == "" {
 		reqId = fmt.Sprintf("%d", time.Now().Unix())
 		ctx.Request.Header.Add("Request-Id", reqId)
+		ctx.Set(requestIdGeneratedKey, true)
 	}
 	method := strings.ToUpper(ctx.Request.Method)
 	path := ctx.Request.URL.Path
@@ -38,17 +42,40 @@ func (entry ginEntry) Prepare(methods ...string) {
 	ginHandlers := func() []gin.HandlerFunc {
 		ret := make([]gin.HandlerFunc, len(entry.acts))
 		for i, act := range entry.acts {
+			act := act
 			ret[i] = func(ctx *gin.Context) {
-				act(constructGinActor(ctx, entry.logger))
+				actor := constructGinActor(ctx, entry.logger)
+				act(actor)
+				closeEventStreams(actor)
 			}
 		}
 		return ret
 	}
+	path := joinPaths(entry.g.BasePath(), entry.path)
+	if (len(methods) == 0 || hasMethod(methods, http.MethodOptions)) && entry.methods.hasImplicit(path) {
+		// gin can't replace a route, prepare OPTIONS before the handler is built
+		panic(fmt.Sprintf("bird: OPTIONS %s prepared after its implicit route", path))
+	}
 	if len(methods) == 0 {
+		entry.methods.register(path)
 		entry.g.Any(entry.path, ginHandlers()...)
 		return
 	}
 	entry.g.Match(methods, entry.path, ginHandlers()...)
+	entry.prepareOptions(methods...)
+}
+
+// prepareOptions queues the implicit OPTIONS route, so that the group
+// middlewares (CORS for example) can see the preflight requests.
+func (entry ginEntry) prepareOptions(methods ...string) {
+	path := joinPaths(entry.g.BasePath(), entry.path)
+	entry.methods.register(path, methods...)
+	entry.methods.implicitOptions(path, func() {
+		entry.g.OPTIONS(entry.path, func(ctx *gin.Context) {
+			ctx.Header("Allow", entry.methods.allow(path))
+			ctx.AbortWithStatus(http.StatusNoContent)
+		})
+	})
 }
 
 type ginActor struct {
@@ -58,39 +85,63 @@ type ginActor struct {
 }
 
 type ginRouter struct {
-	logger logf.Logger
-	r      *gin.RouterGroup
-	g      *gin.Engine
+	logger  logf.Logger
+	r       *gin.RouterGroup
+	g       *gin.Engine
+	methods *routeMethods
 }
 
 var _ Router = &ginRouter{}
 
 func GinRouter(g *gin.Engine, logger logf.Logger) Router {
-	return &ginRouter{logger: logger, g: g, r: &g.RouterGroup}
+	return &ginRouter{logger: logger, g: g, r: &g.RouterGroup, methods: newRouteMethods()}
 }
 
 func (r ginRouter) Use(acts ...HandleFunc) {
 	for _, act := range acts {
-		r.g.Use(func(ctx *gin.Context) {
+		act := act
+		h := func(ctx *gin.Context) {
 			act(constructGinActor(ctx, r.logger))
-		})
+		}
+		// the middlewares of a group only apply to the group
+		if r.r != &r.g.RouterGroup {
+			r.r.Use(h)
+			continue
+		}
+		r.g.Use(h)
 	}
 }
 
 func (r ginRouter) Group(base string) Router {
-	return &ginRouter{r: r.g.Group(base), g: r.g, logger: r.logger.Prefix(base + ": ")}
+	return &ginRouter{r: r.r.Group(base), g: r.g, logger: r.logger.Prefix(base + ": "), methods: r.methods}
 }
 
 func (r ginRouter) ON(path string, acts ...HandleFunc) Entry {
 	return ginEntry{
-		path:   path,
-		logger: r.logger,
-		g:      r.r,
-		acts:   acts,
+		path:    path,
+		logger:  r.logger,
+		g:       r.r,
+		acts:    acts,
+		methods: r.methods,
+	}
+}
+
+func (r ginRouter) WS(path string, handle func(conn Conn), opts ...WSOption) {
+	r.ON(path, WebSocket(handle, opts...)).Prepare(http.MethodGet)
+}
+
+func (r ginRouter) Static(prefix string, fsys fs.FS, opts ...StaticOption) {
+	files := newStaticFiles(fsys, opts...)
+	r.ON(joinPaths(prefix, "/*filepath"), files.handler(func(actor Actor) string {
+		return actor.Param("filepath")
+	})).Prepare(http.MethodGet, http.MethodHead)
+	if dir := strings.TrimSuffix(joinPaths(r.r.BasePath(), prefix), "/"); dir != "" {
+		r.ON(strings.TrimSuffix(prefix, "/"), staticRedirect(dir)).Prepare(http.MethodGet, http.MethodHead)
 	}
 }
 
 func (g ginRouter) HttpHandler() http.Handler {
+	g.methods.prepareImplicit()
 	return g.g
 }
 
@@ -125,7 +176,7 @@ func (g ginActor) Get(key string) (any, bool) {
 }
 
 func (g ginActor) Bind(obj any) error {
-	if err := g.Context.Bind(obj); err != nil {
+	if err := bind(g, obj); err != nil {
 		g.logger.Logf(logf.Error, "bind object: %s", err.Error())
 		return err
 	}
@@ -133,7 +184,17 @@ func (g ginActor) Bind(obj any) error {
 	return nil
 }
 
+func (g ginActor) File(name string) (*UploadedFile, error) {
+	return formFile(g, name)
+}
+
+func (g ginActor) Files(name string) ([]*UploadedFile, error) {
+	return formFiles(g, name)
+}
+
 func (g ginActor) Write(statusCode int, data any) error {
+	recordResponseCode(g, data)
+	writeLinks(g, data)
 	g.Header("Request-Id", g.RequestId())
 	g.JSON(statusCode, data)
 	if len(g.Errors) > 0 {
@@ -143,10 +204,31 @@ func (g ginActor) Write(statusCode int, data any) error {
 	return nil
 }
 
+func (g ginActor) Stream(statusCode int, contentType string, write func(w io.Writer) error) error {
+	err := stream(g, statusCode, contentType, write)
+	if err == nil || g.Writer.Written() {
+		g.Abort()
+	}
+	return err
+}
+
+func (g ginActor) SSE() *EventStream {
+	g.Abort()
+	return newEventStream(g)
+}
+
 func (g ginActor) Next() {
 	g.Context.Next()
 }
 
+func (g ginActor) ServeFile(path string) error {
+	return serveFile(g, path)
+}
+
+func (g ginActor) Attachment(content io.Reader, name string) error {
+	return attachment(g, content, name)
+}
+
 func (g ginActor) GetRequest() *http.Request {
 	return g.Request
 }
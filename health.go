@@ -0,0 +1,286 @@
+package bird
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthFailing  = "failing"
+)
+
+// HealthChecker checks a component, a nil error means healthy
+type HealthChecker interface {
+	Check(ctx context.Context) error
+}
+
+type CheckFunc func(ctx context.Context) error
+
+func (f CheckFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type healthCheck struct {
+	name     string
+	checker  HealthChecker
+	critical bool
+	liveness bool
+	timeout  time.Duration
+}
+
+type HealthCheckOption func(*healthCheck)
+
+// NonCritical reports the failure of the check without failing the probe
+func NonCritical() HealthCheckOption {
+	return func(c *healthCheck) {
+		c.critical = false
+	}
+}
+
+// Liveness includes the check in the liveness probe, only the checks that a
+// restart can fix, e.g. a deadlock detection, should be liveness checks
+func Liveness() HealthCheckOption {
+	return func(c *healthCheck) {
+		c.liveness = true
+	}
+}
+
+func CheckTimeout(timeout time.Duration) HealthCheckOption {
+	return func(c *healthCheck) {
+		c.timeout = timeout
+	}
+}
+
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Critical bool   `json:"critical"`
+	Duration string `json:"duration"`
+}
+
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Health runs the registered checks for the health, readiness and liveness probes
+//
+//	health := bird.NewHealth()
+//	health.Register("db", bird.CheckFunc(db.PingContext), bird.CheckTimeout(time.Second))
+//	health.Register("cache", bird.CheckFunc(cache.Ping), bird.NonCritical())
+//	health.Mount(r)
+type Health struct {
+	mu           sync.RWMutex
+	checks       []healthCheck
+	shuttingDown int32
+}
+
+func NewHealth() *Health {
+	return &Health{}
+}
+
+// Register adds a critical check with a timeout of 5 seconds by default
+func (h *Health) Register(name string, checker HealthChecker, opts ...HealthCheckOption) {
+	c := healthCheck{name: name, checker: checker, critical: true, timeout: 5 * time.Second}
+	for _, apply := range opts {
+		apply(&c)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, c)
+}
+
+// Shutdown fails the readiness probe, so that the traffic is drained before the server stops
+func (h *Health) Shutdown() {
+	atomic.StoreInt32(&h.shuttingDown, 1)
+}
+
+func (h *Health) ShuttingDown() bool {
+	return atomic.LoadInt32(&h.shuttingDown) == 1
+}
+
+// Health runs all the checks
+func (h *Health) Health(ctx context.Context) HealthReport {
+	return h.run(ctx, func(healthCheck) bool { return true })
+}
+
+// Ready runs all the checks, it fails while shutting down
+func (h *Health) Ready(ctx context.Context) HealthReport {
+	if h.ShuttingDown() {
+		return HealthReport{Status: HealthFailing, Checks: map[string]CheckResult{
+			"shutdown": {Status: HealthFailing, Error: "shutting down", Critical: true},
+		}}
+	}
+	return h.Health(ctx)
+}
+
+// Live runs the liveness checks
+func (h *Health) Live(ctx context.Context) HealthReport {
+	return h.run(ctx, func(c healthCheck) bool { return c.liveness })
+}
+
+func (h *Health) run(ctx context.Context, filter func(healthCheck) bool) HealthReport {
+	h.mu.RLock()
+	var checks []healthCheck
+	for _, c := range h.checks {
+		if filter(c) {
+			checks = append(checks, c)
+		}
+	}
+	h.mu.RUnlock()
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c healthCheck) {
+			defer wg.Done()
+			results[i] = c.run(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+	report := HealthReport{Status: HealthOK, Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status == HealthOK {
+			continue
+		}
+		if c.critical {
+			report.Status = HealthFailing
+		} else if report.Status == HealthOK {
+			report.Status = HealthDegraded
+		}
+	}
+	return report
+}
+
+func (c healthCheck) run(ctx context.Context) (ret CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	start := time.Now()
+	ret = CheckResult{Status: HealthOK, Critical: c.critical}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- fmt.Errorf("panic: %v", recovered)
+			}
+		}()
+		done <- c.checker.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timeout after %s", c.timeout)
+		}
+	}
+	ret.Duration = time.Since(start).String()
+	if err != nil {
+		ret.Status = HealthFailing
+		ret.Error = err.Error()
+	}
+	return
+}
+
+type healthMountConfig struct {
+	health, ready, live string
+	plainText           bool
+}
+
+type HealthMountOption func(*healthMountConfig)
+
+// HealthPaths overrides the paths of the probes, an empty path skips the probe
+func HealthPaths(health, ready, live string) HealthMountOption {
+	return func(c *healthMountConfig) {
+		c.health, c.ready, c.live = health, ready, live
+	}
+}
+
+// HealthPlainText responds the probes with plain text instead of the bird envelope
+func HealthPlainText() HealthMountOption {
+	return func(c *healthMountConfig) {
+		c.plainText = true
+	}
+}
+
+// Mount registers /healthz, /readyz and /livez on the router, the probes respond 200
+// if no critical check fails and 503 otherwise. it panics if two probes have the same path
+func (h *Health) Mount(r Router, opts ...HealthMountOption) {
+	c := healthMountConfig{health: "/healthz", ready: "/readyz", live: "/livez"}
+	for _, apply := range opts {
+		apply(&c)
+	}
+	probes := []struct {
+		path  string
+		probe func(context.Context) HealthReport
+	}{
+		{c.health, h.Health},
+		{c.ready, h.Ready},
+		{c.live, h.Live},
+	}
+	seen := map[string]bool{}
+	for _, p := range probes {
+		if p.path == "" {
+			continue
+		}
+		if seen[p.path] {
+			panic(fmt.Sprintf("bird: the health probes share the path %s", p.path))
+		}
+		seen[p.path] = true
+		r.ON(p.path, c.handler(p.probe)).Prepare(http.MethodGet, http.MethodHead)
+	}
+}
+
+func (c healthMountConfig) handler(probe func(context.Context) HealthReport) HandleFunc {
+	return func(actor Actor) {
+		report := probe(actor.GetRequest().Context())
+		status := http.StatusOK
+		if report.Status == HealthFailing {
+			status = http.StatusServiceUnavailable
+		}
+		if !c.plainText {
+			if status == http.StatusOK {
+				actor.Write(status, OK(report))
+				return
+			}
+			actor.Write(status, ResponseBody{Code: CodeUnhealthy, Data: report})
+			return
+		}
+		w := actor.GetResponseWriter()
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		w.Write([]byte(report.text()))
+		abort(actor)
+	}
+}
+
+// text formats the report like the kubernetes probes
+func (r HealthReport) text() string {
+	names := make([]string, 0, len(r.Checks))
+	for name := range r.Checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		c := r.Checks[name]
+		if c.Status == HealthOK {
+			fmt.Fprintf(&b, "[+]%s ok\n", name)
+			continue
+		}
+		fmt.Fprintf(&b, "[-]%s failed: %s\n", name, c.Error)
+	}
+	b.WriteString(r.Status + "\n")
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package bird
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+func TestHealthReport(t *testing.T) {
+	ok := CheckFunc(func(ctx context.Context) error { return nil })
+	fail := CheckFunc(func(ctx context.Context) error { return errors.New("down") })
+	health := NewHealth()
+	health.Register("db", ok, Liveness())
+	health.Register("cache", fail, NonCritical())
+	if report := health.Health(context.Background()); report.Status != HealthDegraded || report.Checks["cache"].Error != "down" {
+		t.Fatal("non-critical failure", report)
+	}
+	if report := health.Live(context.Background()); report.Status != HealthOK || len(report.Checks) != 1 {
+		t.Fatal("liveness checks", report)
+	}
+
+	health.Register("slow", CheckFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}), CheckTimeout(10*time.Millisecond))
+	health.Register("panic", CheckFunc(func(ctx context.Context) error { panic("boom") }))
+	report := health.Health(context.Background())
+	if report.Status != HealthFailing {
+		t.Fatal("critical failure", report)
+	}
+	if report.Checks["slow"].Error != "timeout after 10ms" {
+		t.Fatal("timeout", report.Checks["slow"])
+	}
+	if report.Checks["panic"].Error != "panic: boom" {
+		t.Fatal("panic not recovered", report.Checks["panic"])
+	}
+}
+
+func TestHealthMount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		health := NewHealth()
+		health.Register("db", CheckFunc(func(ctx context.Context) error { return nil }))
+		health.Mount(r)
+		health.Mount(r.Group("/text"), HealthPlainText())
+		h := r.HttpHandler()
+		get := func(path string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			return rec
+		}
+
+		var body struct {
+			Code string       `json:"code"`
+			Data HealthReport `json:"data"`
+		}
+		rec := get("/readyz")
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusOK || body.Code != CodeOK || body.Data.Checks["db"].Status != HealthOK {
+			t.Fatal(name, "ready", rec.Code, rec.Body.String())
+		}
+		if rec = get("/text/healthz"); rec.Code != http.StatusOK || rec.Body.String() != "[+]db ok\nok\n" ||
+			!strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+			t.Fatal(name, "plain text", rec.Code, rec.Body.String())
+		}
+
+		health.Shutdown()
+		if rec = get("/readyz"); rec.Code != http.StatusServiceUnavailable || json.Unmarshal(rec.Body.Bytes(), &body) != nil || body.Code != CodeUnhealthy {
+			t.Fatal(name, "ready while shutting down", rec.Code, rec.Body.String())
+		}
+		if rec = get("/text/readyz"); rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "[-]shutdown failed: shutting down\nfailing\n" {
+			t.Fatal(name, "plain text while shutting down", rec.Code, rec.Body.String())
+		}
+		if rec = get("/livez"); rec.Code != http.StatusOK {
+			t.Fatal(name, "live while shutting down", rec.Code)
+		}
+	}
+}
+
+func TestHealthMountSamePath(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("probes of the same path mounted")
+		}
+	}()
+	NewHealth().Mount(GinRouter(gin.New(), logf.New()), HealthPaths("/health", "/health", ""))
+}
@@ -0,0 +1,65 @@
+package bird
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// H2C serves HTTP/2 over the plain listeners, with prior knowledge or the h2c upgrade,
+// it has no effect with TLS where HTTP/2 is negotiated by ALPN
+func H2C() ServeOption {
+	return func(c *serveConfig) {
+		c.h2c = true
+	}
+}
+
+// HTTP3 serves HTTP/3 over QUIC on the udp address besides the tcp listeners,
+// the responses over tcp advertise it with the Alt-Svc header. it requires TLS.
+//
+//	bird.Serve(ctx, r, bird.ListenTCP(":443"), bird.TLSFiles(cert, key), bird.HTTP3(":443"))
+func HTTP3(addr string) ServeOption {
+	return func(c *serveConfig) {
+		c.http3Addr = addr
+	}
+}
+
+// listenHTTP3 binds the udp address of HTTP/3, the server serves the handler once started
+func (c serveConfig) listenHTTP3(handler http.Handler) (*http3.Server, net.PacketConn, error) {
+	if c.tlsConfig == nil {
+		return nil, nil, errors.New("HTTP/3 requires TLS")
+	}
+	conn, err := net.ListenPacket("udp", c.http3Addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &http3.Server{
+		Addr:           c.http3Addr,
+		TLSConfig:      c.tlsConfig,
+		Handler:        handler,
+		MaxHeaderBytes: http.DefaultMaxHeaderBytes,
+	}, conn, nil
+}
+
+// advertiseHTTP3 sets the Alt-Svc header of HTTP/3 on the responses
+func advertiseHTTP3(h3 *http3.Server, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 3 {
+			// fails until the udp listener is served, the next responses advertise it
+			h3.SetQuicHeaders(w.Header())
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// serveH2C serves HTTP/2 without TLS if enabled
+func (c serveConfig) serveH2C(h http.Handler) http.Handler {
+	if !c.h2c || c.tlsConfig != nil {
+		return h
+	}
+	return h2c.NewHandler(h, &http2.Server{IdleTimeout: c.idleTimeout})
+}
@@ -0,0 +1,418 @@
+package bird
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/google/uuid"
+)
+
+var ErrSlowConsumer = errors.New("slow consumer")
+
+// HubMessage is published on a topic of the hub
+type HubMessage struct {
+	Id    string          `json:"id"`
+	Topic string          `json:"topic"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Broker fans out the messages between the hubs of the instances, adapt your NATS
+// or redis client to it, e.g. for go-redis:
+//
+//	func (b redisBroker) Publish(ctx context.Context, topic string, payload []byte) error {
+//	    return b.rdb.Publish(ctx, topic, payload).Err()
+//	}
+//
+//	func (b redisBroker) Subscribe(topic string, deliver func(payload []byte)) (func(), error) {
+//	    sub := b.rdb.Subscribe(context.Background(), topic)
+//	    go func() {
+//	        for msg := range sub.Channel() {
+//	            deliver([]byte(msg.Payload))
+//	        }
+//	    }()
+//	    return func() { sub.Close() }, nil
+//	}
+type Broker interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe delivers the payloads published on the topic by every instance until unsubscribed
+	Subscribe(topic string, deliver func(payload []byte)) (unsubscribe func(), err error)
+}
+
+// SlowConsumerPolicy decides what happens to a message when the buffer of a subscription is full
+type SlowConsumerPolicy int
+
+const (
+	// DropNewest drops the message, it's the default
+	DropNewest SlowConsumerPolicy = iota
+	// DropOldest drops the oldest message buffered to make room for the message
+	DropOldest
+	// CloseSlow closes the subscription with ErrSlowConsumer
+	CloseSlow
+)
+
+type hubTopic struct {
+	subs map[*Subscription]struct{}
+}
+
+// brokerTopic is the subscription of a topic on the broker, it's shared by the
+// subscriptions of the hub by refs
+type brokerTopic struct {
+	// mu serializes the broker calls of the topic, they're made without the lock of the hub
+	mu          sync.Mutex
+	refs        int
+	unsubscribe func()
+}
+
+// Hub fans out the messages published on the topics to the subscriptions of the instance,
+// and of all the instances with a broker
+//
+//	hub := bird.NewHub(bird.HubBroker(broker))
+//	r.ON("/events", func(actor bird.Actor) {
+//	    sub, _ := hub.Subscribe([]string{"orders"}, bird.SubscriptionBuffer(16))
+//	    defer sub.Close()
+//	    events := actor.SSE()
+//	    defer events.Close()
+//	    sub.ServeSSE(events)
+//	}).Prepare(http.MethodGet)
+//	hub.Publish(ctx, "orders", order)
+type Hub struct {
+	mu      sync.RWMutex
+	topics  map[string]*hubTopic
+	brokers map[string]*brokerTopic
+	broker  Broker
+	logger  logf.Logger
+}
+
+type HubOption func(*Hub)
+
+// HubBroker publishes the messages through the broker, so that the subscriptions of
+// all the instances receive them
+func HubBroker(broker Broker) HubOption {
+	return func(h *Hub) {
+		h.broker = broker
+	}
+}
+
+func HubLogger(logger logf.Logger) HubOption {
+	return func(h *Hub) {
+		h.logger = logger
+	}
+}
+
+func NewHub(opts ...HubOption) *Hub {
+	h := &Hub{topics: make(map[string]*hubTopic), brokers: make(map[string]*brokerTopic), logger: logf.New()}
+	for _, apply := range opts {
+		apply(h)
+	}
+	return h
+}
+
+// Publish encodes the data as JSON and publishes it on the topic
+func (h *Hub) Publish(ctx context.Context, topic string, data any) error {
+	bs, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	msg := HubMessage{Id: uuid.NewString(), Topic: topic, Data: bs}
+	if h.broker == nil {
+		h.deliver(msg)
+		return nil
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return h.broker.Publish(ctx, topic, payload)
+}
+
+// deliver offers the message to the subscriptions of the topic, the slow ones are closed by the policy
+func (h *Hub) deliver(msg HubMessage) {
+	var slow []*Subscription
+	h.mu.RLock()
+	if t, ok := h.topics[msg.Topic]; ok {
+		for sub := range t.subs {
+			if !sub.offer(msg) {
+				slow = append(slow, sub)
+			}
+		}
+	}
+	h.mu.RUnlock()
+	for _, sub := range slow {
+		h.logger.Logf(logf.Warn, "close slow subscription of %s", msg.Topic)
+		sub.closeWith(ErrSlowConsumer)
+	}
+}
+
+func (h *Hub) deliverPayload(payload []byte) {
+	var msg HubMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		h.logger.Logf(logf.Error, "decode hub message: %s", err.Error())
+		return
+	}
+	h.deliver(msg)
+}
+
+// Subscribe subscribes the topics, the subscription must be closed when it's no longer used
+func (h *Hub) Subscribe(topics []string, opts ...SubscriptionOption) (*Subscription, error) {
+	sub := &Subscription{hub: h, topics: topics, buffer: 64, done: make(chan struct{})}
+	for _, apply := range opts {
+		apply(sub)
+	}
+	sub.c = make(chan HubMessage, sub.buffer)
+	for i, topic := range topics {
+		if err := h.subscribeBroker(topic); err != nil {
+			for _, subscribed := range topics[:i] {
+				h.unsubscribeBroker(subscribed)
+			}
+			return nil, err
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range topics {
+		t, ok := h.topics[topic]
+		if !ok {
+			t = &hubTopic{subs: make(map[*Subscription]struct{})}
+			h.topics[topic] = t
+		}
+		t.subs[sub] = struct{}{}
+	}
+	return sub, nil
+}
+
+// leave removes the subscription from the topics, and releases the topics of the broker
+func (h *Hub) leave(sub *Subscription) {
+	h.mu.Lock()
+	for _, topic := range sub.topics {
+		if t, ok := h.topics[topic]; ok {
+			delete(t.subs, sub)
+			if len(t.subs) == 0 {
+				delete(h.topics, topic)
+			}
+		}
+	}
+	h.mu.Unlock()
+	for _, topic := range sub.topics {
+		h.unsubscribeBroker(topic)
+	}
+}
+
+// subscribeBroker takes a ref of the topic on the broker, the first one subscribes it
+func (h *Hub) subscribeBroker(topic string) error {
+	if h.broker == nil {
+		return nil
+	}
+	h.mu.Lock()
+	b, ok := h.brokers[topic]
+	if !ok {
+		b = &brokerTopic{}
+		h.brokers[topic] = b
+	}
+	b.refs++
+	h.mu.Unlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.unsubscribe != nil {
+		return nil
+	}
+	unsubscribe, err := h.broker.Subscribe(topic, h.deliverPayload)
+	if err != nil {
+		h.mu.Lock()
+		if b.refs--; b.refs == 0 {
+			delete(h.brokers, topic)
+		}
+		h.mu.Unlock()
+		return err
+	}
+	b.unsubscribe = unsubscribe
+	return nil
+}
+
+// unsubscribeBroker releases a ref of the topic on the broker, the last one unsubscribes it
+func (h *Hub) unsubscribeBroker(topic string) {
+	if h.broker == nil {
+		return
+	}
+	h.mu.Lock()
+	b, ok := h.brokers[topic]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	b.refs--
+	h.mu.Unlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h.mu.RLock()
+	// a subscription may have taken the topic again while waiting for the broker calls,
+	// it subscribes the topic again after the unsubscription otherwise
+	last := b.refs == 0
+	h.mu.RUnlock()
+	if !last {
+		return
+	}
+	if b.unsubscribe != nil {
+		b.unsubscribe()
+		b.unsubscribe = nil
+	}
+	h.mu.Lock()
+	if b.refs == 0 {
+		delete(h.brokers, topic)
+	}
+	h.mu.Unlock()
+}
+
+// Close closes all the subscriptions
+func (h *Hub) Close() {
+	h.mu.RLock()
+	subs := make(map[*Subscription]struct{})
+	for _, t := range h.topics {
+		for sub := range t.subs {
+			subs[sub] = struct{}{}
+		}
+	}
+	h.mu.RUnlock()
+	for sub := range subs {
+		sub.Close()
+	}
+}
+
+type SubscriptionOption func(*Subscription)
+
+// SubscriptionBuffer sets the number of messages buffered for the subscription, 64 by default
+func SubscriptionBuffer(size int) SubscriptionOption {
+	return func(s *Subscription) {
+		s.buffer = size
+	}
+}
+
+// OnSlowConsumer sets the policy when the buffer of the subscription is full
+func OnSlowConsumer(policy SlowConsumerPolicy) SubscriptionOption {
+	return func(s *Subscription) {
+		s.policy = policy
+	}
+}
+
+// Subscription receives the messages of the topics subscribed
+type Subscription struct {
+	hub     *Hub
+	topics  []string
+	buffer  int
+	policy  SlowConsumerPolicy
+	mu      sync.Mutex
+	c       chan HubMessage
+	done    chan struct{}
+	closed  bool
+	err     error
+	dropped int64
+}
+
+// C receives the messages, it's closed when the subscription is closed
+func (s *Subscription) C() <-chan HubMessage {
+	return s.c
+}
+
+// Done is closed when the subscription is closed
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns ErrSlowConsumer if the subscription was closed for being slow
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Dropped returns the number of messages dropped for being slow
+func (s *Subscription) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+// offer buffers the message without blocking, it reports false if the subscription must be closed
+func (s *Subscription) offer(msg HubMessage) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return true
+	}
+	select {
+	case s.c <- msg:
+		return true
+	default:
+	}
+	switch s.policy {
+	case CloseSlow:
+		return false
+	case DropOldest:
+		select {
+		case <-s.c:
+			atomic.AddInt64(&s.dropped, 1)
+		default:
+		}
+		select {
+		case s.c <- msg:
+		default:
+			atomic.AddInt64(&s.dropped, 1)
+		}
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+	return true
+}
+
+func (s *Subscription) Close() {
+	s.closeWith(nil)
+}
+
+func (s *Subscription) closeWith(err error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed, s.err = true, err
+	close(s.c)
+	close(s.done)
+	s.mu.Unlock()
+	s.hub.leave(s)
+}
+
+// ServeSSE sends the messages as the events named after the topics until the client is gone,
+// or the subscription is closed
+func (s *Subscription) ServeSSE(events *EventStream) error {
+	for {
+		select {
+		case msg, ok := <-s.c:
+			if !ok {
+				return s.Err()
+			}
+			if err := events.Send(msg.Topic, msg.Id, []byte(msg.Data)); err != nil {
+				return err
+			}
+		case <-events.Done():
+			return nil
+		}
+	}
+}
+
+// ServeWS writes the messages as JSON until the connection or the subscription is closed
+func (s *Subscription) ServeWS(conn Conn) error {
+	for {
+		select {
+		case msg, ok := <-s.c:
+			if !ok {
+				return s.Err()
+			}
+			if err := conn.WriteJSON(msg); err != nil {
+				return err
+			}
+		case <-conn.Context().Done():
+			return nil
+		}
+	}
+}
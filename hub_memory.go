@@ -0,0 +1,55 @@
+package bird
+
+import (
+	"context"
+	"sync"
+)
+
+type memoryBroker struct {
+	mu     sync.RWMutex
+	next   int
+	topics map[string]map[int]func(payload []byte)
+}
+
+var _ Broker = &memoryBroker{}
+
+// NewMemoryBroker fans out the messages between the hubs of the process,
+// it stands in for the broker of the instances in tests
+func NewMemoryBroker() Broker {
+	return &memoryBroker{topics: make(map[string]map[int]func(payload []byte))}
+}
+
+func (b *memoryBroker) Publish(ctx context.Context, topic string, payload []byte) error {
+	b.mu.RLock()
+	delivers := make([]func(payload []byte), 0, len(b.topics[topic]))
+	for _, deliver := range b.topics[topic] {
+		delivers = append(delivers, deliver)
+	}
+	b.mu.RUnlock()
+	for _, deliver := range delivers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		deliver(payload)
+	}
+	return nil
+}
+
+func (b *memoryBroker) Subscribe(topic string, deliver func(payload []byte)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[int]func(payload []byte))
+	}
+	id := b.next
+	b.next++
+	b.topics[topic][id] = deliver
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.topics[topic], id)
+		if len(b.topics[topic]) == 0 {
+			delete(b.topics, topic)
+		}
+	}, nil
+}
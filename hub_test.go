@@ -0,0 +1,146 @@
+package bird
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/dev-mockingbird/logf"
+)
+
+func TestHubFanOut(t *testing.T) {
+	broker := NewMemoryBroker()
+	a, b := NewHub(HubBroker(broker)), NewHub(HubBroker(broker))
+	sub, err := b.Subscribe([]string{"orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	if err := a.Publish(context.Background(), "orders", map[string]int{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := <-sub.C(); msg.Topic != "orders" || string(msg.Data) != `{"id":1}` || msg.Id == "" {
+		t.Fatal("message not fanned out between the hubs", msg)
+	}
+}
+
+func TestHubSlowConsumer(t *testing.T) {
+	hub := NewHub()
+	ctx := context.Background()
+	dropping, _ := hub.Subscribe([]string{"t"}, SubscriptionBuffer(1), OnSlowConsumer(DropOldest))
+	closing, _ := hub.Subscribe([]string{"t"}, SubscriptionBuffer(1), OnSlowConsumer(CloseSlow))
+	for i := 0; i < 3; i++ {
+		hub.Publish(ctx, "t", i)
+	}
+	if msg := <-dropping.C(); string(msg.Data) != "2" || dropping.Dropped() != 2 {
+		t.Fatal("oldest messages not dropped", string(msg.Data), dropping.Dropped())
+	}
+	<-closing.Done()
+	if closing.Err() != ErrSlowConsumer {
+		t.Fatal("slow subscription not closed")
+	}
+	dropping.Close()
+	if len(hub.topics) != 0 {
+		t.Fatal("topic not released")
+	}
+}
+
+// hookBroker counts the subscriptions of the topics and runs the hook in Subscribe
+type hookBroker struct {
+	mu     sync.Mutex
+	active map[string]int
+	total  int
+	hook   func(topic string, deliver func([]byte))
+}
+
+func (b *hookBroker) Publish(context.Context, string, []byte) error {
+	return nil
+}
+
+func (b *hookBroker) Subscribe(topic string, deliver func([]byte)) (func(), error) {
+	if b.hook != nil {
+		b.hook(topic, deliver)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.active[topic]++
+	b.total++
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.active[topic]--
+	}, nil
+}
+
+func TestHubBrokerCalls(t *testing.T) {
+	broker := &hookBroker{active: map[string]int{}}
+	hub := NewHub(HubBroker(broker), HubLogger(logf.New(logf.LogLevel(logf.Error))))
+
+	// the broker delivering while subscribing doesn't deadlock
+	broker.hook = func(topic string, deliver func([]byte)) {
+		deliver([]byte(`{"id":"1","topic":"` + topic + `","data":1}`))
+	}
+	sub, err := hub.Subscribe([]string{"orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.Close()
+
+	// a slow broker doesn't block the other topics
+	slow, release := make(chan struct{}), make(chan struct{})
+	broker.hook = func(topic string, deliver func([]byte)) {
+		if topic == "slow" {
+			close(slow)
+			<-release
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sub, _ := hub.Subscribe([]string{"slow"})
+		sub.Close()
+	}()
+	<-slow
+	fast, err := hub.Subscribe([]string{"fast"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hub.Publish(context.Background(), "fast", 1); err != nil {
+		t.Fatal(err)
+	}
+	fast.Close()
+	close(release)
+	<-done
+
+	// the subscriptions and the unsubscriptions of the broker stay paired
+	broker.hook = nil
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sub, err := hub.Subscribe([]string{"shared", "orders"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			sub.Close()
+		}()
+	}
+	wg.Wait()
+	for topic, n := range broker.active {
+		if n != 0 {
+			t.Fatal("broker subscription leaked", topic, n)
+		}
+	}
+	if len(hub.brokers) != 0 || len(hub.topics) != 0 {
+		t.Fatal("topics not released", len(hub.brokers), len(hub.topics))
+	}
+	keep, _ := hub.Subscribe([]string{"shared"})
+	other, _ := hub.Subscribe([]string{"shared"})
+	other.Close()
+	if broker.active["shared"] != 1 {
+		t.Fatal("shared broker subscription not kept", broker.active["shared"])
+	}
+	keep.Close()
+}
@@ -0,0 +1,59 @@
+package bird
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+var keySeq atomic.Int64
+
+// TypedKey is a key of the values set on the actor with the type of the value,
+// see Key
+type TypedKey[T any] struct {
+	name string
+}
+
+// keyed boxes the values of the typed keys, so that the nil values are found on echo as well
+type keyed[T any] struct {
+	value T
+}
+
+// Key returns a new typed key, the keys never collide even if they have the same name,
+// so the middlewares don't overwrite the values of each other. e.g.
+//
+//	var UserKey = bird.Key[*User]("user")
+//
+//	UserKey.Set(actor, user)
+//	user, ok := UserKey.Get(actor)
+func Key[T any](name string) TypedKey[T] {
+	return TypedKey[T]{name: fmt.Sprintf("bird.key.%s#%d", name, keySeq.Add(1))}
+}
+
+// Name is the key of the value on Actor.Set and Actor.Get, e.g. to expect the calls on MockActor
+func (k TypedKey[T]) Name() string {
+	return k.name
+}
+
+func (k TypedKey[T]) Set(actor Actor, value T) {
+	actor.Set(k.name, keyed[T]{value: value})
+}
+
+// Get returns the value of the key, false if it isn't set
+func (k TypedKey[T]) Get(actor Actor) (T, bool) {
+	if v, ok := actor.Get(k.name); ok {
+		if v, ok := v.(keyed[T]); ok {
+			return v.value, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// MustGet returns the value of the key, it panics if the key isn't set
+func (k TypedKey[T]) MustGet(actor Actor) T {
+	v, ok := k.Get(actor)
+	if !ok {
+		panic(fmt.Sprintf("key %s isn't set", k.name))
+	}
+	return v
+}
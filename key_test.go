@@ -0,0 +1,56 @@
+package bird
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	type user struct{ Name string }
+	userKey := Key[*user]("user")
+	otherKey := Key[string]("user")
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		var got *user
+		var found, nilFound, otherFound bool
+		r.Use(func(actor Actor) {
+			userKey.Set(actor, &user{Name: "bird"})
+			actor.Next()
+		})
+		r.ON("/", func(actor Actor) {
+			got, found = userKey.Get(actor)
+			_, otherFound = otherKey.Get(actor)
+			userKey.Set(actor, nil)
+			_, nilFound = userKey.Get(actor)
+		}).Prepare()
+		r.HttpHandler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		if !found || got == nil || got.Name != "bird" {
+			t.Fatal(name, "typed value not found")
+		}
+		if otherFound {
+			t.Fatal(name, "keys of the same name collide")
+		}
+		if !nilFound {
+			t.Fatal(name, "nil value reported missing")
+		}
+	}
+
+	ctrl := gomock.NewController(t)
+	actor := NewMockActor(ctrl)
+	actor.EXPECT().Get(otherKey.Name()).Return(nil, false)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MustGet of a missing key didn't panic")
+		}
+	}()
+	otherKey.MustGet(actor)
+}
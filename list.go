@@ -0,0 +1,290 @@
+package bird
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	birderrors "github.com/dev-mockingbird/errors"
+)
+
+// SortField is a field of the sort parameter, e.g. -created is {Field: "created", Desc: true}
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Filter is a filter parameter, filter[status]=active is {Field: "status", Op: "eq", Value: "active"}
+// and filter[created][gte]=2023-01-01 is {Field: "created", Op: "gte", Value: "2023-01-01"}
+type Filter struct {
+	Field string
+	Op    string
+	Value string
+}
+
+// ListRequest is the request of a list endpoint, see BindList
+type ListRequest struct {
+	// Page starts from 1, it's 1 if the cursor is used
+	Page   int
+	Size   int
+	Cursor string
+	Sort   []SortField
+	// Filters are in the order of the fields, a field repeated has a filter for each value
+	Filters []Filter
+}
+
+// Offset is the offset of the page
+func (l ListRequest) Offset() int {
+	return (l.Page - 1) * l.Size
+}
+
+// Filter returns the value of the eq filter of the field
+func (l ListRequest) Filter(field string) (string, bool) {
+	for _, f := range l.Filters {
+		if f.Field == field && f.Op == "eq" {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+type listConfig struct {
+	defaultSize int
+	maxSize     int
+	sortable    map[string]bool
+	defaultSort string
+	filterable  map[string][]string
+}
+
+type ListOption func(*listConfig)
+
+// ListPageSize sets the default and the max size of the page, 20 and 100 by default
+func ListPageSize(def, max int) ListOption {
+	return func(c *listConfig) {
+		c.defaultSize, c.maxSize = def, max
+	}
+}
+
+// ListSortable allows sorting by the fields, no field is sortable by default
+func ListSortable(fields ...string) ListOption {
+	return func(c *listConfig) {
+		for _, f := range fields {
+			c.sortable[f] = true
+		}
+	}
+}
+
+// ListDefaultSort is the sort if the request has none, e.g. -created,name
+func ListDefaultSort(sort string) ListOption {
+	return func(c *listConfig) {
+		c.defaultSort = sort
+	}
+}
+
+// ListFilterable allows filtering the field by the operators, eq if there's none
+func ListFilterable(field string, ops ...string) ListOption {
+	return func(c *listConfig) {
+		if len(ops) == 0 {
+			ops = []string{"eq"}
+		}
+		c.filterable[field] = ops
+	}
+}
+
+var listKey = Key[ListRequest]("list")
+
+// BindList binds the page, size, cursor, sort and filter query parameters, the sort and the
+// filters are checked with the whitelists of the options. the errors are tagged invalid-arguments
+//
+//	list, err := bird.BindList(actor,
+//	    bird.ListSortable("created", "name"),
+//	    bird.ListDefaultSort("-created"),
+//	    bird.ListFilterable("status"),
+//	    bird.ListFilterable("created", "gte", "lt"))
+func BindList(actor Actor, opts ...ListOption) (ListRequest, error) {
+	c := listConfig{defaultSize: 20, maxSize: 100, sortable: map[string]bool{}, filterable: map[string][]string{}}
+	for _, apply := range opts {
+		apply(&c)
+	}
+	var list ListRequest
+	var err error
+	if list.Page, err = QueryInt(actor, "page", 1); err != nil {
+		return list, err
+	}
+	if list.Page < 1 {
+		return list, birderrors.New("query page: must be at least 1", CodeInvalidArguments)
+	}
+	if list.Size, err = QueryInt(actor, "size", c.defaultSize); err != nil {
+		return list, err
+	}
+	if list.Size < 1 || list.Size > c.maxSize {
+		return list, birderrors.New(fmt.Sprintf("query size: must be between 1 and %d", c.maxSize), CodeInvalidArguments)
+	}
+	if list.Cursor = actor.Query("cursor"); list.Cursor != "" && actor.Query("page") != "" {
+		return list, birderrors.New("query cursor: can't be used with page", CodeInvalidArguments)
+	}
+	if sort := actor.Query("sort"); sort != "" {
+		if list.Sort, err = c.checkSort(parseSort(sort)); err != nil {
+			return list, err
+		}
+	} else {
+		list.Sort = parseSort(c.defaultSort)
+	}
+	if list.Filters, err = c.parseFilters(actor.GetRequest().URL.Query()); err != nil {
+		return list, err
+	}
+	listKey.Set(actor, list)
+	return list, nil
+}
+
+func parseSort(sort string) []SortField {
+	var fields []SortField
+	for _, field := range strings.Split(sort, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, SortField{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")})
+		}
+	}
+	return fields
+}
+
+func (c *listConfig) checkSort(fields []SortField) ([]SortField, error) {
+	for _, f := range fields {
+		if !c.sortable[f.Field] {
+			return nil, birderrors.New(fmt.Sprintf("query sort: can't sort by %s", f.Field), CodeInvalidArguments)
+		}
+	}
+	return fields, nil
+}
+
+// parseFilters parses the filter[field] and filter[field][op] parameters in the order of the fields
+func (c *listConfig) parseFilters(query url.Values) ([]Filter, error) {
+	var filters []Filter
+	for key, values := range query {
+		if !strings.HasPrefix(key, "filter[") {
+			continue
+		}
+		field, op, ok := parseFilterKey(key)
+		if !ok {
+			return nil, birderrors.New(fmt.Sprintf("query %s: malformed filter", key), CodeInvalidArguments)
+		}
+		if !slices.Contains(c.filterable[field], op) {
+			return nil, birderrors.New(fmt.Sprintf("query %s: can't filter %s by %s", key, field, op), CodeInvalidArguments)
+		}
+		for _, value := range values {
+			filters = append(filters, Filter{Field: field, Op: op, Value: value})
+		}
+	}
+	sort.SliceStable(filters, func(i, j int) bool {
+		if filters[i].Field != filters[j].Field {
+			return filters[i].Field < filters[j].Field
+		}
+		return filters[i].Op < filters[j].Op
+	})
+	return filters, nil
+}
+
+// parseFilterKey parses filter[field] and filter[field][op]
+func parseFilterKey(key string) (field, op string, ok bool) {
+	rest := strings.TrimPrefix(key, "filter[")
+	field, rest, ok = strings.Cut(rest, "]")
+	if !ok || field == "" {
+		return "", "", false
+	}
+	if rest == "" {
+		return field, "eq", true
+	}
+	if !strings.HasPrefix(rest, "[") || !strings.HasSuffix(rest, "]") || len(rest) < 3 {
+		return "", "", false
+	}
+	return field, rest[1 : len(rest)-1], true
+}
+
+// Page is the data of the response of a list endpoint
+type Page struct {
+	Items any `json:"items"`
+	// Next is the cursor of the next page, empty on the last page
+	Next string `json:"next,omitempty"`
+	// Total is omitted if it's unknown
+	Total *int64 `json:"total,omitempty"`
+}
+
+// Paged is OK with the pagination metadata, the negative total is unknown. if the
+// request is bound by BindList, Actor.Write sets the Link header of the first, prev,
+// next and last pages as well, the next page of an unknown total is linked if the page is full
+//
+//	actor.Write(http.StatusOK, bird.Paged(orders, next, total))
+func Paged(items any, next string, total int64) ResponseBody {
+	page := Page{Items: items, Next: next}
+	if total >= 0 {
+		page.Total = &total
+	}
+	return OK(page)
+}
+
+// itemCount returns the length of the items, 0 if the items aren't a slice or an array
+func itemCount(items any) int {
+	v := reflect.ValueOf(items)
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		return v.Len()
+	}
+	return 0
+}
+
+// writeLinks sets the Link header of the paged response
+func writeLinks(actor Actor, data any) {
+	var page Page
+	var paged bool
+	switch body := data.(type) {
+	case ResponseBody:
+		page, paged = body.Data.(Page)
+	case *ResponseBody:
+		if body != nil {
+			page, paged = body.Data.(Page)
+		}
+	}
+	list, ok := listKey.Get(actor)
+	if !paged || !ok {
+		return
+	}
+	u := *actor.GetRequest().URL
+	link := func(rel string, set func(q url.Values)) string {
+		q := u.Query()
+		set(q)
+		return fmt.Sprintf(`<%s>; rel="%s"`, (&url.URL{Path: u.Path, RawQuery: q.Encode()}).String(), rel)
+	}
+	atPage := func(n int) func(url.Values) {
+		return func(q url.Values) {
+			q.Del("cursor")
+			q.Set("page", strconv.Itoa(n))
+		}
+	}
+	var links []string
+	if page.Next != "" {
+		links = append(links, link("next", func(q url.Values) {
+			q.Del("page")
+			q.Set("cursor", page.Next)
+		}))
+	} else if list.Cursor == "" {
+		links = append(links, link("first", atPage(1)))
+		if list.Page > 1 {
+			links = append(links, link("prev", atPage(list.Page-1)))
+		}
+		if page.Total != nil {
+			last := int((*page.Total + int64(list.Size) - 1) / int64(list.Size))
+			if list.Page < last {
+				links = append(links, link("next", atPage(list.Page+1)))
+			}
+			links = append(links, link("last", atPage(max(last, 1))))
+		} else if itemCount(page.Items) >= list.Size {
+			// the total is unknown, a full page may have a next one
+			links = append(links, link("next", atPage(list.Page+1)))
+		}
+	}
+	if len(links) > 0 {
+		actor.GetResponseWriter().Header().Set("Link", strings.Join(links, ", "))
+	}
+}
@@ -0,0 +1,107 @@
+package bird
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+func TestList(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		var got ListRequest
+		r.ON("/orders", func(actor Actor) {
+			list, err := BindList(actor, ListPageSize(10, 50), ListSortable("created", "name"), ListDefaultSort("-created"),
+				ListFilterable("status"), ListFilterable("created", "gte", "lt"))
+			if err != nil {
+				actor.Write(http.StatusBadRequest, InvalidArguments(err))
+				return
+			}
+			got = list
+			next := ""
+			if list.Cursor != "" {
+				next = list.Cursor + "+"
+			}
+			actor.Write(http.StatusOK, Paged([]int{1, 2}, next, 45))
+		}).Prepare()
+		r.ON("/events", func(actor Actor) {
+			if _, err := BindList(actor, ListPageSize(2, 10)); err != nil {
+				actor.Write(http.StatusBadRequest, InvalidArguments(err))
+				return
+			}
+			items := []int{1, 2}
+			if actor.Query("page") == "3" {
+				items = items[:1]
+			}
+			actor.Write(http.StatusOK, Paged(items, "", -1))
+		}).Prepare(http.MethodGet)
+		h := r.HttpHandler()
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders?page=2&sort=name,-created&filter[status]=active&filter[created][gte]=2023-01-01", nil))
+		if rec.Code != http.StatusOK || got.Page != 2 || got.Size != 10 || got.Offset() != 10 || len(got.Sort) != 2 || !got.Sort[1].Desc ||
+			len(got.Filters) != 2 || got.Filters[0] != (Filter{Field: "created", Op: "gte", Value: "2023-01-01"}) {
+			t.Fatal(name, "unexpected list request", rec.Code, got)
+		}
+		if status, ok := got.Filter("status"); !ok || status != "active" {
+			t.Fatal(name, "eq filter not found")
+		}
+		var body struct {
+			Code string `json:"code"`
+			Data struct {
+				Items []int `json:"items"`
+				Total int64 `json:"total"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Code != CodeOK || len(body.Data.Items) != 2 || body.Data.Total != 45 {
+			t.Fatal(name, "unexpected paged body", rec.Body.String())
+		}
+		link := rec.Header().Get("Link")
+		for _, rel := range []string{`page=1`, `rel="prev"`, `page=3`, `rel="next"`, `page=5`, `rel="last"`} {
+			if !strings.Contains(link, rel) {
+				t.Fatal(name, "link missing", rel, link)
+			}
+		}
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+		if got.Page != 1 || len(got.Sort) != 1 || got.Sort[0] != (SortField{Field: "created", Desc: true}) {
+			t.Fatal(name, "default sort not applied", got)
+		}
+
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders?cursor=c", nil))
+		if link := rec.Header().Get("Link"); link != `</orders?cursor=c%2B>; rel="next"` {
+			t.Fatal(name, "unexpected cursor link", link)
+		}
+
+		// the total is unknown, the full page links the next one
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events?page=2", nil))
+		if link := rec.Header().Get("Link"); link != `</events?page=1>; rel="first", </events?page=1>; rel="prev", </events?page=3>; rel="next"` {
+			t.Fatal(name, "unexpected link of unknown total", link)
+		}
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events?page=3", nil))
+		if link := rec.Header().Get("Link"); strings.Contains(link, `rel="next"`) {
+			t.Fatal(name, "next linked after the last page", link)
+		}
+
+		for _, query := range []string{"size=51", "sort=secret", "filter[secret]=x", "filter[status][gte]=x", "page=2&cursor=c", "page=zero"} {
+			rec = httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders?"+query, nil))
+			var rejected ResponseBody
+			if err := json.Unmarshal(rec.Body.Bytes(), &rejected); err != nil || rec.Code != http.StatusBadRequest || rejected.Code != CodeInvalidArguments {
+				t.Fatal(name, "invalid list request accepted", query, rec.Code)
+			}
+		}
+	}
+}
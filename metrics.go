@@ -0,0 +1,223 @@
+package bird
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/dev-mockingbird/validate"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	microerrors "go-micro.dev/v4/errors"
+)
+
+// the route label of the requests not matching any route
+const unmatchedRoute = "unmatched"
+
+type metricsConfig struct {
+	namespace  string
+	registerer prometheus.Registerer
+	gatherer   prometheus.Gatherer
+	buckets    []float64
+	sizes      []float64
+}
+
+type MetricsOption func(*metricsConfig)
+
+func MetricsNamespace(namespace string) MetricsOption {
+	return func(c *metricsConfig) {
+		c.namespace = namespace
+	}
+}
+
+// MetricsRegistry registers the metrics to the registry and serves it by Metrics.Handler,
+// prometheus.DefaultRegisterer is used by default
+func MetricsRegistry(registry *prometheus.Registry) MetricsOption {
+	return func(c *metricsConfig) {
+		c.registerer = registry
+		c.gatherer = registry
+	}
+}
+
+// MetricsBuckets sets the buckets of the latency histograms in seconds
+func MetricsBuckets(buckets ...float64) MetricsOption {
+	return func(c *metricsConfig) {
+		c.buckets = buckets
+	}
+}
+
+type Metrics struct {
+	registerer     prometheus.Registerer
+	gatherer       prometheus.Gatherer
+	namespace      string
+	requests       *prometheus.CounterVec
+	latency        *prometheus.HistogramVec
+	inFlight       prometheus.Gauge
+	size           *prometheus.HistogramVec
+	forwardLatency *prometheus.HistogramVec
+	forwards       *prometheus.CounterVec
+}
+
+// NewMetrics registers the request and forward metrics, the requests are labelled
+// by the route pattern, the method, the status and the code of the bird response body.
+// the requests matching no route are labelled "unmatched". calling it again with the
+// same namespace and registry shares the metrics registered already
+//
+//	m := bird.NewMetrics(bird.MetricsNamespace("gateway"))
+//	r.Use(m.Middleware())
+//	r.ON("/metrics", m.Handler()).Prepare(http.MethodGet)
+//	forwarder := m.Forwarder(bird.GetForwarder())
+func NewMetrics(opts ...MetricsOption) *Metrics {
+	c := metricsConfig{
+		namespace:  "bird",
+		registerer: prometheus.DefaultRegisterer,
+		gatherer:   prometheus.DefaultGatherer,
+		buckets:    prometheus.DefBuckets,
+		sizes:      prometheus.ExponentialBuckets(100, 10, 6),
+	}
+	for _, apply := range opts {
+		apply(&c)
+	}
+	labels := []string{"route", "method", "status", "code"}
+	m := &Metrics{
+		registerer: c.registerer,
+		gatherer:   c.gatherer,
+		namespace:  c.namespace,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "The number of the requests handled.",
+		}, labels),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: c.namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "The latency of the requests.",
+			Buckets:   c.buckets,
+		}, labels),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: c.namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "The number of the requests being handled.",
+		}),
+		size: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: c.namespace,
+			Subsystem: "http",
+			Name:      "response_size_bytes",
+			Help:      "The size of the response bodies.",
+			Buckets:   c.sizes,
+		}, labels),
+		forwardLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: c.namespace,
+			Subsystem: "forward",
+			Name:      "duration_seconds",
+			Help:      "The latency of the backend calls.",
+			Buckets:   c.buckets,
+		}, []string{"route", "code"}),
+		forwards: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Subsystem: "forward",
+			Name:      "calls_total",
+			Help:      "The number of the backend calls by the result code.",
+		}, []string{"route", "code"}),
+	}
+	m.requests = register(m.registerer, m.requests)
+	m.latency = register(m.registerer, m.latency)
+	m.inFlight = register(m.registerer, m.inFlight)
+	m.size = register(m.registerer, m.size)
+	m.forwardLatency = register(m.registerer, m.forwardLatency)
+	m.forwards = register(m.registerer, m.forwards)
+	return m
+}
+
+// register registers the collector, the collector registered already is reused,
+// so that the metrics of the same namespace are shared by the routers
+func register[T prometheus.Collector](registerer prometheus.Registerer, c T) T {
+	if err := registerer.Register(c); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return c
+}
+
+// Middleware observes the requests handled by the handlers after it
+func (m *Metrics) Middleware() HandleFunc {
+	return func(actor Actor) {
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+		start := time.Now()
+		actor.Next()
+		route := routePattern(actor)
+		if route == "" {
+			route = unmatchedRoute
+		}
+		labels := prometheus.Labels{
+			"route":  route,
+			"method": actor.GetRequest().Method,
+			"status": strconv.Itoa(responseStatus(actor)),
+			"code":   ResponseCode(actor),
+		}
+		m.requests.With(labels).Inc()
+		m.latency.With(labels).Observe(time.Since(start).Seconds())
+		m.size.With(labels).Observe(float64(responseSize(actor)))
+	}
+}
+
+// Forwarder observes the backend calls of the forwarder, the calls are labelled by the
+// status of the go-micro errors, "ok" for success and "unknown" for the other errors
+func (m *Metrics) Forwarder(forwarder Forwarder) Forwarder {
+	return Forward(func(c Actor, creq any, forward func() error, rules ...validate.Rules) error {
+		return forwarder.Forward(c, creq, func() error {
+			start := time.Now()
+			err := forward()
+			code := CodeOK
+			if err != nil {
+				code = CodeUnkownError
+				if e, ok := err.(*microerrors.Error); ok && e.Status != "" {
+					code = e.Status
+				}
+			}
+			labels := prometheus.Labels{"route": routePattern(c), "code": code}
+			m.forwards.With(labels).Inc()
+			m.forwardLatency.With(labels).Observe(time.Since(start).Seconds())
+			return err
+		}, rules...)
+	})
+}
+
+// ObserveConcurrency reports the limit and the requests in flight of the limiter,
+// observing the name again keeps reporting the limiter observed first
+func (m *Metrics) ObserveConcurrency(name string, limiter ConcurrencyLimiter) {
+	labels := prometheus.Labels{"limiter": name}
+	register(m.registerer, prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   m.namespace,
+		Subsystem:   "concurrency",
+		Name:        "limit",
+		Help:        "The current limit of the concurrency limiter.",
+		ConstLabels: labels,
+	}, func() float64 {
+		return float64(limiter.Limit())
+	}))
+	register(m.registerer, prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   m.namespace,
+		Subsystem:   "concurrency",
+		Name:        "in_flight",
+		Help:        "The requests in flight of the concurrency limiter.",
+		ConstLabels: labels,
+	}, func() float64 {
+		return float64(limiter.InFlight())
+	}))
+}
+
+// Handler serves the metrics in the prometheus exposition format
+func (m *Metrics) Handler() HandleFunc {
+	h := promhttp.HandlerFor(m.gatherer, promhttp.HandlerOpts{})
+	return func(actor Actor) {
+		h.ServeHTTP(actor.GetResponseWriter(), actor.GetRequest())
+	}
+}
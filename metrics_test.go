@@ -0,0 +1,80 @@
+package bird
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		registry := prometheus.NewRegistry()
+		m := NewMetrics(MetricsRegistry(registry))
+		// the second metrics of the registry share the collectors instead of panicking
+		NewMetrics(MetricsRegistry(registry))
+		r.Use(m.Middleware())
+		r.ON("/users/:id", func(actor Actor) {
+			actor.Write(http.StatusNotFound, NotFound(errors.New("user not found")))
+		}).Prepare(http.MethodGet)
+		r.ON("/metrics", m.Handler()).Prepare(http.MethodGet)
+		h := r.HttpHandler()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/7", nil))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nowhere/7", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatal(name, "unmatched path status", rec.Code)
+		}
+
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		body := rec.Body.String()
+		for _, expected := range []string{
+			`bird_http_requests_total{code="not-found",method="GET",route="/users/:id",status="404"} 1`,
+			`bird_http_requests_total{code="",method="GET",route="unmatched",status="404"} 1`,
+		} {
+			if !strings.Contains(body, expected) {
+				t.Fatal(name, "metric not scraped:", expected, "\n", body)
+			}
+		}
+		if strings.Contains(body, "/*") || strings.Contains(body, "/nowhere") {
+			t.Fatal(name, "unmatched path labelled by the path\n", body)
+		}
+	}
+}
+
+func TestMetricsObserveConcurrency(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	limiter := FixedConcurrency(3)
+	NewMetrics(MetricsRegistry(registry)).ObserveConcurrency("api", limiter)
+	// observing again, e.g. by the metrics of another router, doesn't panic
+	NewMetrics(MetricsRegistry(registry)).ObserveConcurrency("api", FixedConcurrency(5))
+	release, ok := limiter.Acquire()
+	if !ok {
+		t.Fatal("request not acquired")
+	}
+	defer release(0, false)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			values[family.GetName()] = metric.GetGauge().GetValue()
+		}
+	}
+	if values["bird_concurrency_limit"] != 3 || values["bird_concurrency_in_flight"] != 1 {
+		t.Fatal("unexpected concurrency metrics", values)
+	}
+}
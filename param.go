@@ -0,0 +1,111 @@
+package bird
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	birderrors "github.com/dev-mockingbird/errors"
+	"github.com/google/uuid"
+)
+
+// parseValue parses the value as Actor.Bind does, the errors are tagged invalid-arguments
+// and name the parameter, e.g. query limit: "ten" isn't an integer
+func parseValue[T any](source, name, value, layout string) (T, error) {
+	var ret T
+	if err := setValue(reflect.ValueOf(&ret).Elem(), value, layout); err != nil {
+		return ret, birderrors.New(fmt.Sprintf("%s %s: %s", source, name, err.Error()), CodeInvalidArguments)
+	}
+	return ret, nil
+}
+
+// ParamValue parses the path parameter into any type Actor.Bind supports, the missing
+// parameter is an error. the errors are tagged invalid-arguments and name the parameter
+//
+//	id, err := bird.ParamInt(actor, "id")
+//	if err != nil {
+//	    actor.Write(http.StatusBadRequest, bird.InvalidArguments(err))
+//	    return
+//	}
+func ParamValue[T any](actor Actor, name string) (T, error) {
+	value := actor.Param(name)
+	if value == "" {
+		var zero T
+		return zero, birderrors.New(fmt.Sprintf("path %s is required", name), CodeInvalidArguments)
+	}
+	return parseValue[T]("path", name, value, "")
+}
+
+func ParamInt(actor Actor, name string) (int, error) {
+	return ParamValue[int](actor, name)
+}
+
+func ParamInt64(actor Actor, name string) (int64, error) {
+	return ParamValue[int64](actor, name)
+}
+
+func ParamUUID(actor Actor, name string) (uuid.UUID, error) {
+	return ParamValue[uuid.UUID](actor, name)
+}
+
+// QueryValue parses the query parameter into any type Actor.Bind supports, def is
+// returned if the parameter is missing. the errors are tagged invalid-arguments
+func QueryValue[T any](actor Actor, name string, def T) (T, error) {
+	value := actor.Query(name)
+	if value == "" {
+		return def, nil
+	}
+	return parseValue[T]("query", name, value, "")
+}
+
+func QueryInt(actor Actor, name string, def int) (int, error) {
+	return QueryValue(actor, name, def)
+}
+
+func QueryInt64(actor Actor, name string, def int64) (int64, error) {
+	return QueryValue(actor, name, def)
+}
+
+func QueryFloat(actor Actor, name string, def float64) (float64, error) {
+	return QueryValue(actor, name, def)
+}
+
+func QueryBool(actor Actor, name string, def bool) (bool, error) {
+	return QueryValue(actor, name, def)
+}
+
+func QueryDuration(actor Actor, name string, def time.Duration) (time.Duration, error) {
+	return QueryValue(actor, name, def)
+}
+
+// QueryUUID returns uuid.Nil if the parameter is missing
+func QueryUUID(actor Actor, name string) (uuid.UUID, error) {
+	return QueryValue(actor, name, uuid.Nil)
+}
+
+// QueryTime parses the query parameter by the layout, time.RFC3339 if it's empty,
+// the zero time is returned if the parameter is missing
+func QueryTime(actor Actor, name, layout string) (time.Time, error) {
+	value := actor.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return parseValue[time.Time]("query", name, value, layout)
+}
+
+// QueryEnum returns the query parameter if it's one of the allowed values, def if it's missing
+//
+//	order, err := bird.QueryEnum(actor, "order", "asc", "asc", "desc")
+func QueryEnum(actor Actor, name, def string, allowed ...string) (string, error) {
+	value := actor.Query(name)
+	if value == "" {
+		return def, nil
+	}
+	for _, a := range allowed {
+		if value == a {
+			return value, nil
+		}
+	}
+	return "", birderrors.New(fmt.Sprintf("query %s: %q isn't one of %s", name, value, strings.Join(allowed, ", ")), CodeInvalidArguments)
+}
@@ -0,0 +1,41 @@
+package bird
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+)
+
+func TestParams(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	actor := NewMockActor(ctrl)
+	id := uuid.New()
+	actor.EXPECT().Param("id").Return(id.String()).AnyTimes()
+	actor.EXPECT().Param("n").Return("").AnyTimes()
+	actor.EXPECT().Query("limit").Return("").AnyTimes()
+	actor.EXPECT().Query("offset").Return("ten").AnyTimes()
+	actor.EXPECT().Query("since").Return("2023-01-02").AnyTimes()
+	actor.EXPECT().Query("order").Return("up").AnyTimes()
+
+	if got, err := ParamUUID(actor, "id"); err != nil || got != id {
+		t.Fatal("path uuid error", got, err)
+	}
+	if _, err := ParamInt(actor, "n"); err == nil || InvalidArguments(err).Data != Msg("path n is required") {
+		t.Fatal("missing path param accepted", err)
+	}
+	if got, err := QueryInt(actor, "limit", 10); err != nil || got != 10 {
+		t.Fatal("default not applied", got, err)
+	}
+	if _, err := QueryInt(actor, "offset", 0); err == nil || ErrorOccurred(err, CodeUnkownError).Code != CodeInvalidArguments ||
+		InvalidArguments(err).Data != Msg(`query offset: "ten" isn't an integer`) {
+		t.Fatal("invalid integer error", err)
+	}
+	if got, err := QueryTime(actor, "since", "2006-01-02"); err != nil || !got.Equal(time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("time error", got, err)
+	}
+	if _, err := QueryEnum(actor, "order", "asc", "asc", "desc"); err == nil || InvalidArguments(err).Data != Msg(`query order: "up" isn't one of asc, desc`) {
+		t.Fatal("enum error", err)
+	}
+}
@@ -0,0 +1,178 @@
+package bird
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dev-mockingbird/errors"
+	"github.com/dev-mockingbird/logf"
+)
+
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// the duration until the quota is fully restored
+	Reset time.Duration
+	// the duration the client should wait before retrying, it's set when not allowed
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the state of the limited keys, the store must apply
+// the algorithms atomically, so that a store can be shared by instances.
+type RateLimitStore interface {
+	// TakeToken takes a token from the bucket of the key, the bucket refills
+	// with rate tokens per second and holds at most burst tokens.
+	TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (RateLimitResult, error)
+	// TakeWindow counts a hit of the key if there were less than limit hits
+	// in the window before now.
+	TakeWindow(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (RateLimitResult, error)
+}
+
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (RateLimitResult, error)
+}
+
+type AllowFunc func(ctx context.Context, key string) (RateLimitResult, error)
+
+func (f AllowFunc) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	return f(ctx, key)
+}
+
+// TokenBucket allows limit requests per period on average and bursts up to burst requests
+func TokenBucket(store RateLimitStore, limit int, per time.Duration, burst int) RateLimiter {
+	rate := float64(limit) / per.Seconds()
+	if burst <= 0 {
+		burst = limit
+	}
+	return AllowFunc(func(ctx context.Context, key string) (RateLimitResult, error) {
+		return store.TakeToken(ctx, key, rate, burst, time.Now())
+	})
+}
+
+// SlidingWindow allows at most limit requests in any window
+func SlidingWindow(store RateLimitStore, limit int, window time.Duration) RateLimiter {
+	return AllowFunc(func(ctx context.Context, key string) (RateLimitResult, error) {
+		return store.TakeWindow(ctx, key, limit, window, time.Now())
+	})
+}
+
+type RateLimitKeyFunc func(Actor) string
+
+// KeyByRemoteIP limits by the ip of the connected peer, it's the default key.
+// Behind a proxy every request shares the proxy ip, use KeyByClientIP then.
+func KeyByRemoteIP() RateLimitKeyFunc {
+	return func(actor Actor) string {
+		return "ip:" + RemoteIP(actor)
+	}
+}
+
+// KeyByClientIP limits by the ip the backend resolves from the forwarding
+// headers. Configure the trusted proxies first (gin Engine.SetTrustedProxies,
+// echo Echo.IPExtractor), otherwise any client gets a fresh bucket by
+// sending its own X-Forwarded-For.
+func KeyByClientIP() RateLimitKeyFunc {
+	return func(actor Actor) string {
+		return "ip:" + ClientIP(actor)
+	}
+}
+
+// KeyByPrincipal limits by the principal set under PrincipalKey,
+// requests without a principal are limited by the remote ip.
+func KeyByPrincipal() RateLimitKeyFunc {
+	return func(actor Actor) string {
+		if principal, ok := actor.Get(PrincipalKey); ok && principal != nil {
+			return fmt.Sprintf("principal:%v", principal)
+		}
+		return "ip:" + RemoteIP(actor)
+	}
+}
+
+// KeyByHeader limits by a header, e.g. the api key
+func KeyByHeader(name string) RateLimitKeyFunc {
+	return func(actor Actor) string {
+		return "header:" + name + ":" + actor.GetRequest().Header.Get(name)
+	}
+}
+
+func KeyByParam(name string) RateLimitKeyFunc {
+	return func(actor Actor) string {
+		return "param:" + name + ":" + actor.Param(name)
+	}
+}
+
+type rateLimitConfig struct {
+	prefix    string
+	key       RateLimitKeyFunc
+	failClose bool
+}
+
+type RateLimitOption func(*rateLimitConfig)
+
+func RateLimitKey(key RateLimitKeyFunc) RateLimitOption {
+	return func(c *rateLimitConfig) {
+		c.key = key
+	}
+}
+
+// RateLimitPrefix namespaces the keys, so that the limits of different
+// routes sharing a store don't count each other
+func RateLimitPrefix(prefix string) RateLimitOption {
+	return func(c *rateLimitConfig) {
+		c.prefix = prefix
+	}
+}
+
+// RateLimitFailClose rejects the requests with 503 and CodeOverloaded when the
+// store is unavailable, by default they are let through.
+func RateLimitFailClose() RateLimitOption {
+	return func(c *rateLimitConfig) {
+		c.failClose = true
+	}
+}
+
+// RateLimit rejects the requests over the limit with 429
+//
+//	store := bird.NewMemoryRateLimitStore()
+//	r.Use(bird.RateLimit(bird.TokenBucket(store, 100, time.Minute, 20), bird.RateLimitKey(bird.KeyByPrincipal())))
+func RateLimit(limiter RateLimiter, opts ...RateLimitOption) HandleFunc {
+	c := rateLimitConfig{key: KeyByRemoteIP()}
+	for _, apply := range opts {
+		apply(&c)
+	}
+	return func(actor Actor) {
+		key := c.prefix + c.key(actor)
+		res, err := limiter.Allow(actor.GetRequest().Context(), key)
+		if err != nil {
+			actor.Logger().Logf(logf.Error, "rate limit [%s]: %s", key, err.Error())
+			if c.failClose {
+				actor.Write(http.StatusServiceUnavailable, Overloaded(err, "service unavailable, please try again"))
+				return
+			}
+			actor.Next()
+			return
+		}
+		header := actor.GetResponseWriter().Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			header.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			actor.Logger().Logf(logf.Trace, "rate limited [%s]", key)
+			actor.Write(http.StatusTooManyRequests, RateLimited(errors.New("too many requests, please try again later", CodeRateLimited)))
+			return
+		}
+		actor.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,115 @@
+package bird
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type tokenBucket struct {
+	tokens  float64
+	last    time.Time
+	expires time.Time
+}
+
+type windowLog struct {
+	hits    []time.Time
+	expires time.Time
+}
+
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	windows   map[string]*windowLog
+	lastSweep time.Time
+}
+
+var _ RateLimitStore = &memoryRateLimitStore{}
+
+// NewMemoryRateLimitStore keeps the limits in process, it doesn't share limits between instances
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		windows: make(map[string]*windowLog),
+	}
+}
+
+func (s *memoryRateLimitStore) TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		s.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed*rate)
+		b.last = now
+	}
+	res := RateLimitResult{Limit: burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsDuration((1 - b.tokens) / rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = secondsDuration((float64(burst) - b.tokens) / rate)
+	b.expires = now.Add(res.Reset)
+	return res, nil
+}
+
+func (s *memoryRateLimitStore) TakeWindow(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	w, ok := s.windows[key]
+	if !ok {
+		w = &windowLog{}
+		s.windows[key] = w
+	}
+	start := now.Add(-window)
+	i := 0
+	for i < len(w.hits) && !w.hits[i].After(start) {
+		i++
+	}
+	w.hits = w.hits[i:]
+	res := RateLimitResult{Limit: limit}
+	if len(w.hits) < limit {
+		w.hits = append(w.hits, now)
+		res.Allowed = true
+	}
+	res.Remaining = limit - len(w.hits)
+	if len(w.hits) > 0 {
+		res.Reset = w.hits[len(w.hits)-1].Add(window).Sub(now)
+		if !res.Allowed {
+			res.RetryAfter = w.hits[0].Add(window).Sub(now)
+		}
+	}
+	w.expires = now.Add(window)
+	return res, nil
+}
+
+// sweep drops the expired keys, it runs at most once a minute
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.After(b.expires) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, w := range s.windows {
+		if now.After(w.expires) {
+			delete(s.windows, key)
+		}
+	}
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,160 @@
+package bird
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RedisEvaler runs a lua script on redis, adapt your redis client to it, e.g. for go-redis:
+//
+//	bird.RedisEval(func(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+//	    return rdb.Eval(ctx, script, keys, args...).Result()
+//	})
+type RedisEvaler interface {
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+type RedisEval func(ctx context.Context, script string, keys []string, args ...any) (any, error)
+
+func (f RedisEval) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	return f(ctx, script, keys, args...)
+}
+
+// returns {allowed, tokens}, tokens is a string as redis truncates lua numbers to integers
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate)
+	ts = now
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate) + 1)
+return {allowed, tostring(tokens)}
+`
+
+// returns {allowed, count, oldest hit, newest hit}
+const slidingWindowScript = `
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], window)
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+local newest = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+return {allowed, count, oldest[2] or now, newest[2] or now}
+`
+
+type redisRateLimitStore struct {
+	redis  RedisEvaler
+	prefix string
+}
+
+var _ RateLimitStore = &redisRateLimitStore{}
+
+// NewRedisRateLimitStore shares the limits between instances through redis,
+// the keys are stored under the prefix
+func NewRedisRateLimitStore(redis RedisEvaler, prefix string) RateLimitStore {
+	return &redisRateLimitStore{redis: redis, prefix: prefix}
+}
+
+func (s *redisRateLimitStore) TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (RateLimitResult, error) {
+	// the rate is passed in tokens per millisecond
+	perMs := rate / 1000
+	ret, err := s.redis.Eval(ctx, tokenBucketScript, []string{s.prefix + key}, perMs, burst, now.UnixMilli())
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	values, err := redisNumbers(ret, 2)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	tokens := values[1]
+	res := RateLimitResult{
+		Allowed:   values[0] == 1,
+		Limit:     burst,
+		Remaining: int(tokens),
+		Reset:     secondsDuration((float64(burst) - tokens) / rate),
+	}
+	if !res.Allowed {
+		res.RetryAfter = secondsDuration((1 - tokens) / rate)
+	}
+	return res, nil
+}
+
+func (s *redisRateLimitStore) TakeWindow(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (RateLimitResult, error) {
+	nowMs := now.UnixMilli()
+	ret, err := s.redis.Eval(ctx, slidingWindowScript, []string{s.prefix + key}, limit, window.Milliseconds(), nowMs, fmt.Sprintf("%d-%s", nowMs, uuid.NewString()))
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	values, err := redisNumbers(ret, 4)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	res := RateLimitResult{
+		Allowed:   values[0] == 1,
+		Limit:     limit,
+		Remaining: limit - int(values[1]),
+		Reset:     time.Duration(int64(values[3])+window.Milliseconds()-nowMs) * time.Millisecond,
+	}
+	if !res.Allowed {
+		res.RetryAfter = time.Duration(int64(values[2])+window.Milliseconds()-nowMs) * time.Millisecond
+	}
+	return res, nil
+}
+
+// redisNumbers parses the array reply of the scripts, the clients return
+// the integers as int64 and the bulk strings as string or []byte
+func redisNumbers(ret any, n int) ([]float64, error) {
+	items, ok := ret.([]any)
+	if !ok || len(items) < n {
+		return nil, fmt.Errorf("unexpected redis reply: %#v", ret)
+	}
+	values := make([]float64, n)
+	for i := 0; i < n; i++ {
+		switch v := items[i].(type) {
+		case int64:
+			values[i] = float64(v)
+		case int:
+			values[i] = float64(v)
+		case float64:
+			values[i] = v
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected redis reply: %#v", ret)
+			}
+			values[i] = f
+		case []byte:
+			f, err := strconv.ParseFloat(string(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected redis reply: %#v", ret)
+			}
+			values[i] = f
+		default:
+			return nil, fmt.Errorf("unexpected redis reply: %#v", ret)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,136 @@
+package bird
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+func testTakeToken(t *testing.T, store RateLimitStore) {
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if res, err := store.TakeToken(context.Background(), "k", 1, 3, now); err != nil || !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("token %d not allowed: %v", i, err)
+		}
+	}
+	res, _ := store.TakeToken(context.Background(), "k", 1, 3, now)
+	if res.Allowed || res.RetryAfter != time.Second {
+		t.Fatal("empty bucket allowed")
+	}
+	if res, _ := store.TakeToken(context.Background(), "k", 1, 3, now.Add(time.Second)); !res.Allowed {
+		t.Fatal("bucket not refilled")
+	}
+}
+
+func testTakeWindow(t *testing.T, store RateLimitStore) {
+	now := time.Now()
+	store.TakeWindow(context.Background(), "k", 2, time.Minute, now)
+	store.TakeWindow(context.Background(), "k", 2, time.Minute, now.Add(30*time.Second))
+	res, err := store.TakeWindow(context.Background(), "k", 2, time.Minute, now.Add(40*time.Second))
+	if err != nil || res.Allowed || res.RetryAfter != 20*time.Second {
+		t.Fatal("window exceeded", err)
+	}
+	if res, _ := store.TakeWindow(context.Background(), "k", 2, time.Minute, now.Add(61*time.Second)); !res.Allowed || res.Remaining != 0 {
+		t.Fatal("window not slided")
+	}
+}
+
+func TestMemoryRateLimitStore_TakeToken(t *testing.T) {
+	testTakeToken(t, NewMemoryRateLimitStore())
+}
+
+func TestMemoryRateLimitStore_TakeWindow(t *testing.T) {
+	testTakeWindow(t, NewMemoryRateLimitStore())
+}
+
+// newTestRedisStore runs the scripts on miniredis, a redis in memory
+func newTestRedisStore(t *testing.T) RateLimitStore {
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewRedisRateLimitStore(RedisEval(func(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+		return rdb.Eval(ctx, script, keys, args...).Result()
+	}), "rl:")
+}
+
+func TestRedisRateLimitStore_TakeToken(t *testing.T) {
+	testTakeToken(t, newTestRedisStore(t))
+}
+
+func TestRedisRateLimitStore_TakeWindow(t *testing.T) {
+	testTakeWindow(t, newTestRedisStore(t))
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		r.Use(RateLimit(SlidingWindow(NewMemoryRateLimitStore(), 1, time.Minute)))
+		r.ON("/", func(actor Actor) {
+			actor.Write(http.StatusOK, OK(nil))
+		}).Prepare(http.MethodGet)
+		h := r.HttpHandler()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Remaining") != "0" {
+			t.Fatal(name, "first request limited")
+		}
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+			t.Fatal(name, "second request not limited")
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		req.Header.Set("X-Real-IP", "203.0.113.7")
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatal(name, "forwarding headers got a fresh bucket")
+		}
+	}
+}
+
+func TestRateLimitFailClose(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	failing := AllowFunc(func(context.Context, string) (RateLimitResult, error) {
+		return RateLimitResult{}, errors.New("store down")
+	})
+	for _, failClose := range []bool{false, true} {
+		opts := []RateLimitOption{}
+		if failClose {
+			opts = append(opts, RateLimitFailClose())
+		}
+		r := GinRouter(gin.New(), logf.New())
+		r.Use(RateLimit(failing, opts...))
+		r.ON("/", func(actor Actor) {
+			actor.Write(http.StatusOK, OK(nil))
+		}).Prepare(http.MethodGet)
+		rec := httptest.NewRecorder()
+		r.HttpHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if !failClose {
+			if rec.Code != http.StatusOK {
+				t.Fatalf("fail open: %d", rec.Code)
+			}
+			continue
+		}
+		var body ResponseBody
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusServiceUnavailable || body.Code != CodeOverloaded {
+			t.Fatalf("fail close: %d %s", rec.Code, body.Code)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package bird
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/dev-mockingbird/logf"
+)
+
+// PanicReporter reports the recovered panics, e.g. to an error tracker
+type PanicReporter func(actor Actor, recovered any, stack []byte)
+
+// Recover catches the panics of the handlers after it and writes UnknownError with 500,
+// use it as the first middleware of the router.
+//
+//	r.Use(bird.Recover(func(actor bird.Actor, recovered any, stack []byte) {
+//	    sentry.CurrentHub().Recover(recovered)
+//	}))
+func Recover(reporters ...PanicReporter) HandleFunc {
+	return func(actor Actor) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// the server aborts the response silently for it
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			stack := debug.Stack()
+			actor.Logger().Logf(logf.Error, "panic recovered: %v\n%s", recovered, stack)
+			for _, report := range reporters {
+				report(actor, recovered, stack)
+			}
+			if responseWritten(actor) {
+				abort(actor)
+				return
+			}
+			err := fmt.Errorf("panic: %v", recovered)
+			actor.Write(http.StatusInternalServerError, UnknownError(err, "internal server error, please try again"))
+		}()
+		actor.Next()
+	}
+}
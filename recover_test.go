@@ -0,0 +1,73 @@
+package bird
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+func TestRecover(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	logger := bufferLogger{&buf}
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logger),
+		"echo": EchoRouter(echo.New(), logger),
+	} {
+		buf.Reset()
+		var reported any
+		r.Use(Recover(func(actor Actor, recovered any, stack []byte) {
+			reported = recovered
+		}))
+		r.ON("/panic", func(actor Actor) {
+			panic("boom")
+		}).Prepare(http.MethodGet)
+		r.ON("/abort", func(actor Actor) {
+			panic(http.ErrAbortHandler)
+		}).Prepare(http.MethodGet)
+		h := r.HttpHandler()
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+		var body ResponseBody
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusInternalServerError || body.Code != CodeUnkownError {
+			t.Fatal(name, "panic not written as unknown error", rec.Code, rec.Body.String())
+		}
+		if reported != "boom" {
+			t.Fatal(name, "panic not reported", reported)
+		}
+		if log := buf.String(); !strings.Contains(log, "panic recovered: boom") || !strings.Contains(log, "recover_test.go") {
+			t.Fatal(name, "panic not logged with the stack", log)
+		}
+
+		func() {
+			defer func() {
+				if recovered := recover(); recovered != http.ErrAbortHandler {
+					t.Fatal(name, "abort handler not panicked again", recovered)
+				}
+			}()
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+		}()
+	}
+}
+
+// bufferLogger logs into the buffer, logf.New always logs to stdout
+type bufferLogger struct {
+	buf *bytes.Buffer
+}
+
+func (l bufferLogger) Logf(level logf.Level, format string, v ...any) {
+	fmt.Fprintf(l.buf, format+"\n", v...)
+}
+
+func (l bufferLogger) Prefix(string) logf.Logger {
+	return l
+}
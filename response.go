@@ -10,6 +10,13 @@ const (
 	CodeUnkownError      = "unknown"
 	CodeBadFormat        = "bad-format"
 	CodeUnauthorized     = "unauthorized"
+	CodeCSRFInvalid      = "csrf-invalid"
+	CodeRateLimited      = "rate-limited"
+	CodeOverloaded       = "overloaded"
+	CodeUnhealthy        = "unhealthy"
+	CodeNotFound         = "not-found"
+	CodePayloadTooLarge  = "payload-too-large"
+	CodeInvalidCursor    = "invalid-cursor"
 )
 
 type ResponseBody struct {
@@ -17,6 +24,30 @@ type ResponseBody struct {
 	Data any    `json:"data,omitempty"`
 }
 
+// the key under which Actor.Write records the code of the response body
+const ResponseCodeKey = "bird.response-code"
+
+func recordResponseCode(actor Actor, data any) {
+	switch body := data.(type) {
+	case ResponseBody:
+		actor.Set(ResponseCodeKey, body.Code)
+	case *ResponseBody:
+		if body != nil {
+			actor.Set(ResponseCodeKey, body.Code)
+		}
+	}
+}
+
+// ResponseCode returns the code of the response body written by Actor.Write
+func ResponseCode(actor Actor) string {
+	if code, ok := actor.Get(ResponseCodeKey); ok {
+		if s, ok := code.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
 type Message struct {
 	Msg string `json:"msg,omitempty"`
 }
@@ -44,6 +75,30 @@ func Unauthorized(err error, msg ...string) ResponseBody {
 	return ErrorOccurred(err, CodeUnauthorized, msg...)
 }
 
+func CSRFInvalid(err error, msg ...string) ResponseBody {
+	return ErrorOccurred(err, CodeCSRFInvalid, msg...)
+}
+
+func RateLimited(err error, msg ...string) ResponseBody {
+	return ErrorOccurred(err, CodeRateLimited, msg...)
+}
+
+func Overloaded(err error, msg ...string) ResponseBody {
+	return ErrorOccurred(err, CodeOverloaded, msg...)
+}
+
+func NotFound(err error, msg ...string) ResponseBody {
+	return ErrorOccurred(err, CodeNotFound, msg...)
+}
+
+func PayloadTooLarge(err error, msg ...string) ResponseBody {
+	return ErrorOccurred(err, CodePayloadTooLarge, msg...)
+}
+
+func InvalidCursor(err error, msg ...string) ResponseBody {
+	return ErrorOccurred(err, CodeInvalidCursor, msg...)
+}
+
 // parse err tag and msg.
 // for untagged err, it can't produce the err detail as message for client
 // only the tagged most ancient ancestor error can produce client message and code
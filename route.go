@@ -0,0 +1,252 @@
+package bird
+
+import (
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// routeMethods records the methods prepared on every absolute path of a router,
+// it's shared by the router and all of its groups.
+type routeMethods struct {
+	mu      sync.Mutex
+	methods map[string][]string
+	// explicit records the paths prepared with OPTIONS, implicit the paths
+	// the implicit OPTIONS route is registered for
+	explicit map[string]bool
+	implicit map[string]bool
+	pending  []implicitOptions
+	// ready is set by the first Router.HttpHandler, the implicit routes of
+	// the paths prepared after it are registered right away
+	ready bool
+}
+
+type implicitOptions struct {
+	path    string
+	prepare func()
+}
+
+func newRouteMethods() *routeMethods {
+	return &routeMethods{
+		methods:  make(map[string][]string),
+		explicit: make(map[string]bool),
+		implicit: make(map[string]bool),
+	}
+}
+
+// register records the methods of the path, the path prepared without methods
+// has OPTIONS explicitly
+func (r *routeMethods) register(path string, methods ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(methods) == 0 {
+		r.explicit[path] = true
+		return
+	}
+	for _, method := range methods {
+		method = strings.ToUpper(method)
+		if method == http.MethodOptions {
+			r.explicit[path] = true
+		}
+		r.methods[path] = append(r.methods[path], method)
+	}
+}
+
+func hasMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// has reports whether the path is prepared
+func (r *routeMethods) has(path string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.methods[path]) > 0 || r.explicit[path]
+}
+
+// implicitOptions queues the implicit OPTIONS route of the path, which answers the
+// preflight requests of the routes prepared without OPTIONS. the routes are registered
+// by prepareImplicit, so that the OPTIONS routes prepared later by the user replace them.
+// once the handler is built, the route is registered immediately
+func (r *routeMethods) implicitOptions(path string, prepare func()) {
+	r.mu.Lock()
+	if !r.ready {
+		r.pending = append(r.pending, implicitOptions{path: path, prepare: prepare})
+		r.mu.Unlock()
+		return
+	}
+	if r.explicit[path] || r.implicit[path] {
+		r.mu.Unlock()
+		return
+	}
+	r.implicit[path] = true
+	r.mu.Unlock()
+	prepare()
+}
+
+// hasImplicit reports whether the implicit OPTIONS route of the path is registered
+func (r *routeMethods) hasImplicit(path string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.implicit[path]
+}
+
+// prepareImplicit registers the queued implicit OPTIONS routes of the paths without
+// explicit OPTIONS, Router.HttpHandler calls it
+func (r *routeMethods) prepareImplicit() {
+	r.mu.Lock()
+	r.ready = true
+	var prepare []func()
+	for _, o := range r.pending {
+		if r.explicit[o.path] || r.implicit[o.path] {
+			continue
+		}
+		r.implicit[o.path] = true
+		prepare = append(prepare, o.prepare)
+	}
+	r.pending = nil
+	r.mu.Unlock()
+	for _, p := range prepare {
+		p()
+	}
+}
+
+// allow returns the Allow header value of the path
+func (r *routeMethods) allow(path string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := map[string]bool{http.MethodOptions: true}
+	ret := []string{http.MethodOptions}
+	for _, method := range r.methods[path] {
+		if !seen[method] {
+			seen[method] = true
+			ret = append(ret, method)
+		}
+	}
+	sort.Strings(ret)
+	return strings.Join(ret, ", ")
+}
+
+func joinPaths(base, relative string) string {
+	if relative == "" {
+		return base
+	}
+	ret := path.Join(base, relative)
+	if strings.HasSuffix(relative, "/") && !strings.HasSuffix(ret, "/") {
+		return ret + "/"
+	}
+	return ret
+}
+
+// abortWithStatus writes the status without body and stops the handler chain
+func abortWithStatus(actor Actor, statusCode int) {
+	switch a := actor.(type) {
+	case GinContextGetter:
+		a.GetContext().AbortWithStatus(statusCode)
+	case EchoContextGetter:
+		a.GetContext().NoContent(statusCode)
+	default:
+		actor.GetResponseWriter().WriteHeader(statusCode)
+	}
+}
+
+// routePattern returns the pattern of the matched route, e.g. /users/:id
+func routePattern(actor Actor) string {
+	switch a := actor.(type) {
+	case GinContextGetter:
+		return a.GetContext().FullPath()
+	case EchoContextGetter:
+		// the group middlewares of echo match any path by /*, so the path
+		// not prepared by the router isn't a route
+		ctx := a.GetContext()
+		if routes, ok := ctx.Get(echoRoutesKey).(*routeMethods); ok && !routes.has(ctx.Path()) {
+			return ""
+		}
+		return ctx.Path()
+	}
+	return ""
+}
+
+// responseSize returns the bytes of the response body written
+func responseSize(actor Actor) int64 {
+	switch a := actor.(type) {
+	case GinContextGetter:
+		if size := a.GetContext().Writer.Size(); size > 0 {
+			return int64(size)
+		}
+	case EchoContextGetter:
+		return a.GetContext().Response().Size
+	}
+	return 0
+}
+
+// responseWritten reports whether the response header has been written
+func responseWritten(actor Actor) bool {
+	switch a := actor.(type) {
+	case GinContextGetter:
+		return a.GetContext().Writer.Written()
+	case EchoContextGetter:
+		return a.GetContext().Response().Committed
+	}
+	return false
+}
+
+// abort stops the handler chain without writing anything
+func abort(actor Actor) {
+	if a, ok := actor.(GinContextGetter); ok {
+		a.GetContext().Abort()
+	}
+}
+
+// setRequest replaces the request of the actor, e.g. to carry a new context
+func setRequest(actor Actor, req *http.Request) {
+	switch a := actor.(type) {
+	case GinContextGetter:
+		a.GetContext().Request = req
+	case EchoContextGetter:
+		a.GetContext().SetRequest(req)
+	default:
+		*actor.GetRequest() = *req
+	}
+}
+
+// ClientIP returns the client ip of the request as the backend resolves it,
+// the backends trust X-Forwarded-For and X-Real-IP unless the trusted proxies
+// are configured (gin Engine.SetTrustedProxies, echo Echo.IPExtractor)
+func ClientIP(actor Actor) string {
+	switch a := actor.(type) {
+	case GinContextGetter:
+		return a.GetContext().ClientIP()
+	case EchoContextGetter:
+		return a.GetContext().RealIP()
+	}
+	return RemoteIP(actor)
+}
+
+// RemoteIP returns the ip of the peer connected to the server, ignoring the
+// forwarding headers
+func RemoteIP(actor Actor) string {
+	addr := actor.GetRequest().RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// responseStatus returns the status code written, or to be written, for the request
+func responseStatus(actor Actor) int {
+	switch a := actor.(type) {
+	case GinContextGetter:
+		return a.GetContext().Writer.Status()
+	case EchoContextGetter:
+		return a.GetContext().Response().Status
+	}
+	return 0
+}
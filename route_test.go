@@ -0,0 +1,108 @@
+package bird
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+func TestImplicitOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		ok := func(actor Actor) {
+			actor.Write(http.StatusOK, OK(nil))
+		}
+		r.ON("/x", ok).Prepare(http.MethodGet)
+		r.ON("/x", func(actor Actor) {
+			actor.Write(http.StatusAccepted, OK("options"))
+		}).Prepare(http.MethodOptions)
+		r.ON("/y", ok).Prepare(http.MethodGet, http.MethodPost)
+		h := r.HttpHandler()
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/x", nil))
+		if rec.Code != http.StatusAccepted {
+			t.Fatal(name, "explicit OPTIONS replaced", rec.Code)
+		}
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/y", nil))
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Allow") != "GET, OPTIONS, POST" {
+			t.Fatal(name, "implicit OPTIONS error", rec.Code, rec.Header().Get("Allow"))
+		}
+		// preparing the handler again doesn't register the implicit routes twice
+		r.HttpHandler()
+
+		// the routes prepared after the handler get the implicit route too
+		r.ON("/z", ok).Prepare(http.MethodPut)
+		r.ON("/z", ok).Prepare(http.MethodDelete)
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/z", nil))
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Allow") != "DELETE, OPTIONS, PUT" {
+			t.Fatal(name, "implicit OPTIONS of late route error", rec.Code, rec.Header().Get("Allow"))
+		}
+	}
+}
+
+func TestNestedGroup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		api := r.Group("/api")
+		api.Use(CORS(CORSAllowOrigins("https://example.com")))
+		api.Group("/v1").ON("/x", func(actor Actor) {
+			actor.Write(http.StatusOK, OK(nil))
+		}).Prepare(http.MethodGet)
+		h := r.HttpHandler()
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/x", nil)
+		req.Header.Set("Origin", "https://example.com")
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "https://example.com" {
+			t.Fatal(name, "nested group lost the parent", rec.Code, rec.Header())
+		}
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodOptions, "/api/v1/x", nil)
+		req.Header.Set("Origin", "https://example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Methods") == "" {
+			t.Fatal(name, "preflight of nested group error", rec.Code)
+		}
+	}
+}
+
+func TestLateExplicitOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	options := func(actor Actor) {
+		actor.Write(http.StatusAccepted, OK("options"))
+	}
+	e := EchoRouter(echo.New(), logf.New())
+	e.ON("/x", options).Prepare(http.MethodGet)
+	h := e.HttpHandler()
+	e.ON("/x", options).Prepare(http.MethodOptions)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/x", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatal("echo explicit OPTIONS not replacing the implicit route", rec.Code)
+	}
+
+	g := GinRouter(gin.New(), logf.New())
+	g.ON("/x", options).Prepare(http.MethodGet)
+	g.HttpHandler()
+	defer func() {
+		if err := recover(); err == nil {
+			t.Fatal("gin explicit OPTIONS after the implicit route not refused")
+		}
+	}()
+	g.ON("/x", options).Prepare(http.MethodOptions)
+}
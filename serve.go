@@ -0,0 +1,396 @@
+package bird
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// ShutdownHook runs after the server stopped accepting requests,
+// the context is done when the shutdown deadline exceeds
+type ShutdownHook func(ctx context.Context) error
+
+type serveConfig struct {
+	listeners         []func() ([]net.Listener, error)
+	tlsConfig         *tls.Config
+	clientCAs         *x509.CertPool
+	clientAuth        tls.ClientAuthType
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	shutdownTimeout   time.Duration
+	shutdownDelay     time.Duration
+	signals           []os.Signal
+	hooks             []ShutdownHook
+	health            *Health
+	logger            logf.Logger
+	certFile, keyFile string
+	disableHTTP2      bool
+	h2c               bool
+	http3Addr         string
+	err               error
+}
+
+type ServeOption func(*serveConfig)
+
+// ListenTCP serves on the tcp address, it's the default with ":8080"
+func ListenTCP(addr string) ServeOption {
+	return func(c *serveConfig) {
+		c.listeners = append(c.listeners, func() ([]net.Listener, error) {
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return nil, err
+			}
+			return []net.Listener{ln}, nil
+		})
+	}
+}
+
+// ListenUnix serves on the unix socket, the stale socket file is removed
+func ListenUnix(path string, mode os.FileMode) ServeOption {
+	return func(c *serveConfig) {
+		c.listeners = append(c.listeners, func() ([]net.Listener, error) {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			ln, err := net.Listen("unix", path)
+			if err != nil {
+				return nil, err
+			}
+			if mode != 0 {
+				if err := os.Chmod(path, mode); err != nil {
+					ln.Close()
+					return nil, err
+				}
+			}
+			return []net.Listener{ln}, nil
+		})
+	}
+}
+
+// ListenSystemd serves on the sockets passed by systemd socket activation
+func ListenSystemd() ServeOption {
+	return func(c *serveConfig) {
+		c.listeners = append(c.listeners, systemdListeners)
+	}
+}
+
+// Listener serves on the listener
+func Listener(ln net.Listener) ServeOption {
+	return func(c *serveConfig) {
+		c.listeners = append(c.listeners, func() ([]net.Listener, error) {
+			return []net.Listener{ln}, nil
+		})
+	}
+}
+
+func TLSConfig(config *tls.Config) ServeOption {
+	return func(c *serveConfig) {
+		c.tlsConfig = config
+	}
+}
+
+// Timeouts sets the read, write and idle timeouts of the server, the read header
+// timeout is the read timeout unless it's 0, then it's 10 seconds, see ReadHeaderTimeout
+func Timeouts(read, write, idle time.Duration) ServeOption {
+	return func(c *serveConfig) {
+		c.readTimeout = read
+		c.writeTimeout = write
+		c.idleTimeout = idle
+	}
+}
+
+// ReadHeaderTimeout overrides the read header timeout, which defends the server from
+// the clients sending the headers slowly
+func ReadHeaderTimeout(timeout time.Duration) ServeOption {
+	return func(c *serveConfig) {
+		c.readHeaderTimeout = timeout
+	}
+}
+
+// ShutdownTimeout is the deadline to drain the requests in flight, 30 seconds by default
+func ShutdownTimeout(timeout time.Duration) ServeOption {
+	return func(c *serveConfig) {
+		c.shutdownTimeout = timeout
+	}
+}
+
+// ShutdownSignals overrides the signals to shutdown, SIGINT and SIGTERM by default,
+// no signal leaves the shutdown to the context
+func ShutdownSignals(signals ...os.Signal) ServeOption {
+	return func(c *serveConfig) {
+		c.signals = signals
+	}
+}
+
+// OnShutdown adds the hooks, they run in order after the requests are drained
+func OnShutdown(hooks ...ShutdownHook) ServeOption {
+	return func(c *serveConfig) {
+		c.hooks = append(c.hooks, hooks...)
+	}
+}
+
+// ServeHealth fails the readiness of the health when shutting down, and keeps serving
+// for delay before draining, so that the load balancers can see the readiness failing
+func ServeHealth(health *Health, delay time.Duration) ServeOption {
+	return func(c *serveConfig) {
+		c.health = health
+		c.shutdownDelay = delay
+	}
+}
+
+func ServeLogger(logger logf.Logger) ServeOption {
+	return func(c *serveConfig) {
+		c.logger = logger
+	}
+}
+
+// Serve serves the router until the context is done or a shutdown signal is received,
+// then drains the requests in flight and runs the shutdown hooks.
+//
+//	err := bird.Serve(ctx, r,
+//	    bird.ListenTCP(":8080"),
+//	    bird.Timeouts(10*time.Second, 30*time.Second, time.Minute),
+//	    bird.ServeHealth(health, 5*time.Second),
+//	    bird.OnShutdown(func(ctx context.Context) error { return db.Close() }))
+func Serve(ctx context.Context, router Router, opts ...ServeOption) error {
+	c := serveConfig{
+		shutdownTimeout: 30 * time.Second,
+		signals:         []os.Signal{os.Interrupt, syscall.SIGTERM},
+		logger:          logf.New(),
+	}
+	for _, apply := range opts {
+		apply(&c)
+	}
+	if c.err != nil {
+		return c.err
+	}
+	if c.readHeaderTimeout == 0 {
+		c.readHeaderTimeout = 10 * time.Second
+		if c.readTimeout > 0 {
+			c.readHeaderTimeout = c.readTimeout
+		}
+	}
+	if len(c.listeners) == 0 {
+		ListenTCP(":8080")(&c)
+	}
+	if err := c.prepareTLS(); err != nil {
+		return err
+	}
+	listeners, err := c.listen()
+	if err != nil {
+		return err
+	}
+	tracker := &inFlightTracker{shutdown: make(chan struct{})}
+	handler := tracker.track(router.HttpHandler())
+	var h3 *http3.Server
+	var h3Conn net.PacketConn
+	if c.http3Addr != "" {
+		if h3, h3Conn, err = c.listenHTTP3(handler); err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return err
+		}
+		handler = advertiseHTTP3(h3, handler)
+	}
+	srv := &http.Server{
+		Handler:           c.serveH2C(handler),
+		TLSConfig:         c.tlsConfig,
+		ReadTimeout:       c.readTimeout,
+		ReadHeaderTimeout: c.readHeaderTimeout,
+		WriteTimeout:      c.writeTimeout,
+		IdleTimeout:       c.idleTimeout,
+	}
+	if c.disableHTTP2 {
+		srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+	// the long-lived requests, e.g. the websockets, are told to finish when the shutdown starts
+	srv.RegisterOnShutdown(func() {
+		close(tracker.shutdown)
+	})
+	return c.serve(ctx, srv, listeners, h3, h3Conn, tracker)
+}
+
+func (c serveConfig) listen() ([]net.Listener, error) {
+	var listeners []net.Listener
+	for _, listen := range c.listeners {
+		lns, err := listen()
+		if err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, lns...)
+	}
+	if c.tlsConfig != nil {
+		for i, ln := range listeners {
+			listeners[i] = tls.NewListener(ln, c.tlsConfig)
+		}
+	}
+	return listeners, nil
+}
+
+func (c serveConfig) serve(ctx context.Context, srv *http.Server, listeners []net.Listener, h3 *http3.Server, h3Conn net.PacketConn, tracker *inFlightTracker) error {
+	if len(c.signals) > 0 {
+		// NotifyContext without signals would be notified of all the signals, e.g. SIGURG
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, c.signals...)
+		defer stop()
+	}
+	errs := make(chan error, len(listeners)+1)
+	for _, ln := range listeners {
+		c.logger.Logf(logf.Info, "serve on %s://%s", ln.Addr().Network(), ln.Addr())
+		go func(ln net.Listener) {
+			if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		}(ln)
+	}
+	if h3 != nil {
+		c.logger.Logf(logf.Info, "serve HTTP/3 on udp://%s", h3Conn.LocalAddr())
+		go func() {
+			if err := h3.Serve(h3Conn); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		}()
+	}
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		c.logger.Logf(logf.Info, "shutting down")
+	case serveErr = <-errs:
+		c.logger.Logf(logf.Error, "serve: %s", serveErr.Error())
+	}
+	if c.health != nil {
+		c.health.Shutdown()
+		time.Sleep(c.shutdownDelay)
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), c.shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		c.logger.Logf(logf.Warn, "shutdown: %s, %d requests in flight are closed", err.Error(), tracker.count())
+		srv.Close()
+	} else if err := tracker.wait(shutdownCtx); err != nil {
+		// the hijacked connections aren't waited by http.Server.Shutdown
+		c.logger.Logf(logf.Warn, "shutdown: %s, %d requests in flight are abandoned", err.Error(), tracker.count())
+	}
+	if h3 != nil {
+		// the QUIC connections are closed with the listener, after the requests are drained
+		h3.Close()
+		h3Conn.Close()
+	}
+	for _, hook := range c.hooks {
+		if err := hook(shutdownCtx); err != nil {
+			c.logger.Logf(logf.Error, "shutdown hook: %s", err.Error())
+		}
+	}
+	c.logger.Logf(logf.Info, "server stopped")
+	return serveErr
+}
+
+type shutdownKey struct{}
+
+// ServerShutdown returns the channel closed when Serve starts shutting down, the long-lived
+// handlers watch it to finish before the shutdown timeout. the event streams and the websockets
+// are closed with it. it's nil, which never closes, if the request isn't served by Serve
+func ServerShutdown(ctx context.Context) <-chan struct{} {
+	shutdown, _ := ctx.Value(shutdownKey{}).(chan struct{})
+	return shutdown
+}
+
+// inFlightTracker counts the requests in flight, and carries the shutdown channel in their context
+type inFlightTracker struct {
+	n        int64
+	mu       sync.Mutex
+	idle     chan struct{}
+	shutdown chan struct{}
+}
+
+func (t *inFlightTracker) track(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t.shutdown != nil {
+			r = r.WithContext(context.WithValue(r.Context(), shutdownKey{}, t.shutdown))
+		}
+		atomic.AddInt64(&t.n, 1)
+		defer func() {
+			if atomic.AddInt64(&t.n, -1) == 0 {
+				t.mu.Lock()
+				if t.idle != nil {
+					close(t.idle)
+					t.idle = nil
+				}
+				t.mu.Unlock()
+			}
+		}()
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (t *inFlightTracker) count() int {
+	return int(atomic.LoadInt64(&t.n))
+}
+
+func (t *inFlightTracker) wait(ctx context.Context) error {
+	t.mu.Lock()
+	if atomic.LoadInt64(&t.n) == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// systemdListeners returns the sockets passed by systemd, see sd_listen_fds(3)
+func systemdListeners() ([]net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, errors.New("no sockets passed by systemd")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, errors.New("no sockets passed by systemd")
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	const listenFdsStart = 3
+	listeners := make([]net.Listener, 0, n)
+	for fd := listenFdsStart; fd < listenFdsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), "systemd-"+strconv.Itoa(fd))
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return nil, fmt.Errorf("systemd socket %d: %w", fd, err)
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
@@ -0,0 +1,253 @@
+package bird
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func TestServeShutdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := GinRouter(gin.New(), logf.New())
+	started, release := make(chan struct{}), make(chan struct{})
+	r.ON("/slow", func(actor Actor) {
+		close(started)
+		<-release
+		actor.Write(http.StatusOK, OK(nil))
+	}).Prepare(http.MethodGet)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	hooked := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- Serve(ctx, r, Listener(ln), ShutdownSignals(), ShutdownTimeout(5*time.Second),
+			OnShutdown(func(context.Context) error {
+				close(hooked)
+				return nil
+			}),
+			ServeLogger(logf.New(logf.LogLevel(logf.Error))))
+	}()
+
+	// no shutdown signal means no signal handling, the server keeps serving
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatal("server stopped without shutdown", err)
+	default:
+	}
+	resp := make(chan *http.Response)
+	go func() {
+		res, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			t.Error(err)
+		}
+		resp <- res
+	}()
+	<-started
+	cancel()
+	select {
+	case <-done:
+		t.Fatal("requests in flight not drained")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	if res := <-resp; res == nil || res.StatusCode != http.StatusOK {
+		t.Fatal("request in flight not served")
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-hooked:
+	default:
+		t.Fatal("shutdown hook not run")
+	}
+	if _, err := http.Get("http://" + ln.Addr().String() + "/slow"); err == nil {
+		t.Fatal("served after shutdown")
+	}
+}
+
+func TestServeShutdownLongLived(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := GinRouter(gin.New(), logf.New())
+	r.WS("/ws", func(conn Conn) {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	watched := make(chan struct{})
+	r.ON("/events", func(actor Actor) {
+		events := actor.SSE()
+		<-events.Done()
+	}).Prepare(http.MethodGet)
+	r.ON("/export", func(actor Actor) {
+		actor.Stream(http.StatusOK, ContentTypeNDJSON, func(w io.Writer) error {
+			w.Write([]byte("{}\n"))
+			<-ServerShutdown(actor.GetRequest().Context())
+			close(watched)
+			return nil
+		})
+	}).Prepare(http.MethodGet)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Serve(ctx, r, Listener(ln), ShutdownSignals(), ShutdownTimeout(10*time.Second),
+			ServeLogger(logf.New(logf.LogLevel(logf.Error))))
+	}()
+	addr := ln.Addr().String()
+	ws, _, err := websocket.DefaultDialer.Dial("ws://"+addr+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	events, err := http.Get("http://" + addr + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Body.Close()
+	export, err := http.Get("http://" + addr + "/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer export.Body.Close()
+	export.Body.Read(make([]byte, 3))
+
+	start := time.Now()
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatal("shutdown waited for the long-lived requests", elapsed)
+	}
+	if _, _, err := ws.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatal("websocket not closed going away", err)
+	}
+	if _, err := io.ReadAll(events.Body); err != nil {
+		t.Fatal("event stream not ended", err)
+	}
+	select {
+	case <-watched:
+	default:
+		t.Fatal("stream not told to shutdown")
+	}
+}
+
+func TestServeReadHeaderTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the timeouts of 0 don't drop the read header timeout
+	go Serve(ctx, GinRouter(gin.New(), logf.New()), Listener(ln), ShutdownSignals(),
+		ReadHeaderTimeout(100*time.Millisecond), Timeouts(0, 0, 0), ServeLogger(logf.New(logf.LogLevel(logf.Error))))
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n"))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadAll(conn); err != nil {
+		t.Fatal("slow headers not timed out", err)
+	}
+}
+
+func TestServeUnix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := GinRouter(gin.New(), logf.New())
+	r.ON("/ping", func(actor Actor) {
+		actor.Write(http.StatusOK, OK("pong"))
+	}).Prepare(http.MethodGet)
+	path := filepath.Join(t.TempDir(), "bird.sock")
+	// the stale socket file is removed
+	os.WriteFile(path, nil, 0o600)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Serve(ctx, r, ListenUnix(path, 0o660), ServeLogger(logf.New(logf.LogLevel(logf.Error))))
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	client := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", path)
+	}}}
+	var res *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		if res, err = client.Get("http://bird/ping"); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != `{"code":"ok","data":"pong"}` {
+		t.Fatal("unix socket not served", res.StatusCode, string(body))
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o660 {
+		t.Fatal("socket mode not set", err)
+	}
+}
+
+func TestInFlightTracker(t *testing.T) {
+	tracker := &inFlightTracker{}
+	release := make(chan struct{})
+	h := tracker.track(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	go h.ServeHTTP(nil, nil)
+	for tracker.count() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := tracker.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("waited with a request in flight", err)
+	}
+	close(release)
+	if err := tracker.wait(context.Background()); err != nil || tracker.count() != 0 {
+		t.Fatal("idle tracker not released", err)
+	}
+}
+
+func TestSystemdListeners(t *testing.T) {
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+	if _, err := systemdListeners(); err == nil {
+		t.Fatal("sockets of another process accepted")
+	}
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "0")
+	if _, err := systemdListeners(); err == nil {
+		t.Fatal("no sockets accepted")
+	}
+}
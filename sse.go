@@ -0,0 +1,200 @@
+package bird
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dev-mockingbird/logf"
+)
+
+const ContentTypeEventStream = "text/event-stream"
+
+var ErrEventStreamClosed = errors.New("event stream closed")
+
+// EventStream writes the Server-Sent Events of a request, it's safe for concurrent use.
+// the writes fail once the client is gone.
+//
+//	events := actor.SSE()
+//	defer events.Close()
+//	events.Heartbeat(15 * time.Second)
+//	for n := range notifications.Since(events.LastEventID()) {
+//	    if err := events.Send("notification", n.Id, n); err != nil {
+//	        return
+//	    }
+//	}
+type EventStream struct {
+	mu          sync.Mutex
+	w           *streamWriter
+	logger      logf.Logger
+	lastEventId string
+	closed      chan struct{}
+	closeOnce   sync.Once
+	done        chan struct{}
+}
+
+// eventStreamsKey is the key of the event streams of the request
+const eventStreamsKey = "bird.event_streams"
+
+// newEventStream writes the header of the event stream of the actor, the stream is
+// closed by closeEventStreams once the handler returns
+func newEventStream(actor Actor) *EventStream {
+	req := actor.GetRequest()
+	w := actor.GetResponseWriter()
+	w.Header().Set("Request-Id", actor.RequestId())
+	s := &EventStream{
+		w: &streamWriter{
+			ctx:         req.Context(),
+			w:           w,
+			statusCode:  http.StatusOK,
+			contentType: ContentTypeEventStream,
+		},
+		logger:      actor.Logger(),
+		lastEventId: req.Header.Get("Last-Event-ID"),
+		closed:      make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		select {
+		case <-s.closed:
+		case <-req.Context().Done():
+		case <-ServerShutdown(req.Context()):
+			s.Close()
+		}
+	}()
+	v, _ := actor.Get(eventStreamsKey)
+	streams, _ := v.([]*EventStream)
+	actor.Set(eventStreamsKey, append(streams, s))
+	s.w.writeHeader()
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return s
+}
+
+// closeEventStreams closes the event streams of the request, so that the heartbeats
+// don't write after the handler returns
+func closeEventStreams(actor Actor) {
+	if streams, ok := actor.Get(eventStreamsKey); ok {
+		if streams, ok := streams.([]*EventStream); ok {
+			for _, s := range streams {
+				s.Close()
+			}
+		}
+	}
+}
+
+// LastEventID returns the id of the last event the client received before reconnecting
+func (s *EventStream) LastEventID() string {
+	return s.lastEventId
+}
+
+// Send writes an event, the data is written as is if it's a string or bytes, as JSON otherwise.
+// the event and the id are optional
+func (s *EventStream) Send(event, id string, data any) error {
+	var payload string
+	switch d := data.(type) {
+	case string:
+		payload = d
+	case []byte:
+		payload = string(d)
+	default:
+		bs, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		payload = string(bs)
+	}
+	var b strings.Builder
+	if id != "" {
+		fmt.Fprintf(&b, "id: %s\n", oneLine(id))
+	}
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", oneLine(event))
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(payload, "\r\n", "\n"), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	if err := s.write(b.String()); err != nil {
+		return err
+	}
+	s.logger.Logf(logf.Trace, "send event %q, id %q", event, id)
+	return nil
+}
+
+// Retry tells the client to wait for the duration before reconnecting
+func (s *EventStream) Retry(d time.Duration) error {
+	return s.write(fmt.Sprintf("retry: %d\n\n", d.Milliseconds()))
+}
+
+// Comment writes a comment, which is ignored by the client
+func (s *EventStream) Comment(text string) error {
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(&b, ": %s\n", line)
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+// Heartbeat writes a comment every interval until the stream is closed or the request is done,
+// so that the proxies don't close the idle connection
+func (s *EventStream) Heartbeat(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Comment("heartbeat"); err != nil {
+					return
+				}
+			case <-s.w.ctx.Done():
+				return
+			case <-s.closed:
+				return
+			}
+		}
+	}()
+}
+
+// Done is closed when the client is gone or the stream is closed, the stream is closed
+// when the server shuts down as well
+func (s *EventStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close stops the heartbeat, the stream is closed once the handler returns as well
+func (s *EventStream) Close() {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		close(s.closed)
+	})
+}
+
+func (s *EventStream) write(msg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.closed:
+		return ErrEventStreamClosed
+	default:
+	}
+	if _, err := s.w.Write([]byte(msg)); err != nil {
+		s.logger.Logf(logf.Debug, "write event stream: %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// oneLine drops the line breaks, which would end the field
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,108 @@
+package bird
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+func TestEventStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		var lastEventId string
+		r.ON("/events", func(actor Actor) {
+			events := actor.SSE()
+			defer events.Close()
+			lastEventId = events.LastEventID()
+			events.Retry(3 * time.Second)
+			events.Send("greeting", "1", "hello\nworld")
+			events.Send("", "id\n2", map[string]int{"n": 2})
+			events.Comment("bye")
+		}).Prepare(http.MethodGet)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/events", nil)
+		req.Header.Set("Last-Event-ID", "41")
+		r.HttpHandler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != ContentTypeEventStream || lastEventId != "41" {
+			t.Fatal(name, "event stream", rec.Code, rec.Header(), lastEventId)
+		}
+		expected := "retry: 3000\n\n" +
+			"id: 1\nevent: greeting\ndata: hello\ndata: world\n\n" +
+			"id: id2\ndata: {\"n\":2}\n\n" +
+			": bye\n\n"
+		if rec.Body.String() != expected {
+			t.Fatalf("%s event framing:\n%q\nexpected\n%q", name, rec.Body.String(), expected)
+		}
+	}
+}
+
+func TestEventStreamHeartbeat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		streams := make(chan *EventStream, 2)
+		// the handlers return without closing the streams
+		r.ON("/events", func(actor Actor) {
+			events := actor.SSE()
+			streams <- events
+			events.Heartbeat(5 * time.Millisecond)
+			<-actor.GetRequest().Context().Done()
+		}).Prepare(http.MethodGet)
+		r.ON("/returned", func(actor Actor) {
+			events := actor.SSE()
+			streams <- events
+			events.Heartbeat(time.Hour)
+		}).Prepare(http.MethodGet)
+		srv := httptest.NewServer(r.HttpHandler())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events", nil)
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		buf := make([]byte, len(": heartbeat\n\n"))
+		if _, err := io.ReadFull(rsp.Body, buf); err != nil || string(buf) != ": heartbeat\n\n" {
+			t.Fatal(name, "heartbeat", string(buf), err)
+		}
+		cancel()
+		rsp.Body.Close()
+		waitStreamDone(t, name, <-streams)
+
+		rsp, err = http.Get(srv.URL + "/returned")
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if body, _ := io.ReadAll(rsp.Body); strings.Contains(string(body), "heartbeat") {
+			t.Fatal(name, "heartbeat before the interval", string(body))
+		}
+		rsp.Body.Close()
+		waitStreamDone(t, name, <-streams)
+		srv.Close()
+	}
+}
+
+func waitStreamDone(t *testing.T, name string, events *EventStream) {
+	t.Helper()
+	select {
+	case <-events.Done():
+	case <-time.After(time.Second):
+		t.Fatal(name, "event stream not done with the request")
+	}
+	if err := events.Comment("heartbeat"); err == nil {
+		t.Fatal(name, "event stream written after the request")
+	}
+}
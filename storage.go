@@ -0,0 +1,128 @@
+package bird
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// Storage stores the objects, e.g. the uploaded files, under their keys.
+// opening a missing object fails with an error of fs.ErrNotExist, so does deleting
+// it except on S3, which deletes the missing objects silently
+type Storage interface {
+	// Save stores the object, the size is -1 if it's unknown
+	Save(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type localStorage struct {
+	dir string
+}
+
+var _ Storage = localStorage{}
+
+// NewLocalStorage stores the objects as the files under the directory
+func NewLocalStorage(dir string) Storage {
+	return localStorage{dir: dir}
+}
+
+// path returns the file of the key, the key can't escape the directory
+func (s localStorage) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+func (s localStorage) Save(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	file := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, contextReader{ctx: ctx, r: r}); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+func (s localStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(s.path(key))
+}
+
+func (s localStorage) Delete(ctx context.Context, key string) error {
+	return os.Remove(s.path(key))
+}
+
+type memoryObject struct {
+	data        []byte
+	contentType string
+}
+
+type memoryStorage struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+var _ Storage = &memoryStorage{}
+
+// NewMemoryStorage stores the objects in memory, for the tests
+func NewMemoryStorage() Storage {
+	return &memoryStorage{objects: make(map[string]memoryObject)}
+}
+
+func (s *memoryStorage) Save(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(contextReader{ctx: ctx, r: r})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = memoryObject{data: data, contentType: contentType}
+	return nil
+}
+
+func (s *memoryStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	obj, ok := s.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("open %s: %w", key, fs.ErrNotExist)
+	}
+	return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
+
+func (s *memoryStorage) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[key]; !ok {
+		return fmt.Errorf("delete %s: %w", key, fs.ErrNotExist)
+	}
+	delete(s.objects, key)
+	return nil
+}
+
+// contextReader stops reading once the context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
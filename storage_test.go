@@ -0,0 +1,162 @@
+package bird
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testStorage runs the round trip of the objects through the storage
+func testStorage(t *testing.T, storage Storage) {
+	ctx := context.Background()
+	key := "avatars/me 1+1.png"
+	if err := storage.Save(ctx, key, strings.NewReader("first"), 5, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	// the object of unknown size replaces the saved one
+	if err := storage.Save(ctx, key, io.MultiReader(strings.NewReader("sec"), strings.NewReader("ond")), -1, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := storage.Open(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || string(data) != "second" {
+		t.Fatal("unexpected object", string(data), err)
+	}
+	if err := storage.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.Open(ctx, key); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("deleted object opened", err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := storage.Save(cancelled, key, strings.NewReader("third"), 5, ""); err == nil {
+		t.Fatal("object saved with the context cancelled")
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	dir := t.TempDir()
+	storage := NewLocalStorage(dir)
+	testStorage(t, storage)
+	if err := storage.Delete(context.Background(), "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("missing object deleted", err)
+	}
+	// the key can't escape the directory
+	if err := storage.Save(context.Background(), "../../escaped", strings.NewReader("x"), 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped")); err != nil {
+		t.Fatal("object saved out of the directory", err)
+	}
+}
+
+func TestMemoryStorage(t *testing.T) {
+	storage := NewMemoryStorage()
+	testStorage(t, storage)
+	if err := storage.Delete(context.Background(), "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("missing object deleted", err)
+	}
+}
+
+// fakeS3 is an S3 stand-in, it checks the signatures and keeps the objects in memory
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	// hosts and paths records the hosts and the escaped paths of the requests
+	hosts, paths []string
+	secret       string
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hosts = append(s.hosts, r.Host)
+	s.paths = append(s.paths, r.URL.EscapedPath())
+	// sign the request again with the secret and the date of the request
+	signed := r.Clone(context.Background())
+	signed.URL.Host = r.Host
+	signed.Header.Del("Authorization")
+	date, _ := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	signV4(signed, r.Header.Get("X-Amz-Content-Sha256"), "key", s.secret, "us-east-1", "s3", date)
+	if signed.Header.Get("Authorization") != r.Header.Get("Authorization") {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+		return
+	}
+	key := r.Host + r.URL.Path
+	switch r.Method {
+	case http.MethodPut:
+		if r.ContentLength < 0 {
+			w.WriteHeader(http.StatusLengthRequired)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		s.objects[key] = data
+	case http.MethodGet:
+		data, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code></Error>")
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		// S3 deletes the missing objects as well
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	s3 := &fakeS3{objects: map[string][]byte{}, secret: "secret"}
+	srv := httptest.NewServer(s3)
+	defer srv.Close()
+	// the virtual hosted buckets are dialed to the stand-in as well
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+		},
+	}}
+	config := S3Config{
+		Endpoint:        srv.URL + "/",
+		Region:          "us-east-1",
+		Bucket:          "uploads",
+		AccessKeyId:     "key",
+		SecretAccessKey: "secret",
+		PathStyle:       true,
+		Client:          client,
+	}
+	testStorage(t, NewS3Storage(config))
+	if s3.paths[0] != "/uploads/avatars/me%201%2B1.png" || s3.hosts[0] != srv.Listener.Addr().String() {
+		t.Fatal("unexpected path style url", s3.hosts[0], s3.paths[0])
+	}
+
+	config.PathStyle = false
+	s3.hosts, s3.paths = nil, nil
+	testStorage(t, NewS3Storage(config))
+	if s3.paths[0] != "/avatars/me%201%2B1.png" || s3.hosts[0] != "uploads."+srv.Listener.Addr().String() {
+		t.Fatal("unexpected virtual hosted url", s3.hosts[0], s3.paths[0])
+	}
+
+	config.SecretAccessKey = "wrong"
+	err := NewS3Storage(config).Save(context.Background(), "x", bytes.NewReader(nil), 0, "")
+	if err == nil || errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), "403 Forbidden") || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Fatal("unexpected error of the rejected request", err)
+	}
+}
@@ -0,0 +1,92 @@
+package bird
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/dev-mockingbird/logf"
+)
+
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// streamWriter writes the header on the first write, and flushes every write to the client.
+// the writes fail with the error of the request context once the client is gone
+type streamWriter struct {
+	ctx         context.Context
+	w           http.ResponseWriter
+	statusCode  int
+	contentType string
+	written     bool
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if err := s.ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.writeHeader()
+	n, err := s.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, nil
+}
+
+func (s *streamWriter) writeHeader() {
+	if s.written {
+		return
+	}
+	s.written = true
+	h := s.w.Header()
+	h.Set("Content-Type", s.contentType)
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	s.w.WriteHeader(s.statusCode)
+}
+
+// stream implements Actor.Stream on the response writer of the actor
+func stream(actor Actor, statusCode int, contentType string, write func(w io.Writer) error) error {
+	w := actor.GetResponseWriter()
+	w.Header().Set("Request-Id", actor.RequestId())
+	sw := &streamWriter{ctx: actor.GetRequest().Context(), w: w, statusCode: statusCode, contentType: contentType}
+	if err := write(sw); err != nil {
+		if sw.written {
+			actor.Logger().Logf(logf.Warn, "stream interrupted: %s", err.Error())
+		}
+		return err
+	}
+	if !sw.written {
+		sw.writeHeader()
+	}
+	return nil
+}
+
+// NDJSONEncoder writes the values as newline-delimited JSON
+type NDJSONEncoder struct {
+	enc *json.Encoder
+}
+
+func (e NDJSONEncoder) Encode(v any) error {
+	return e.enc.Encode(v)
+}
+
+// NDJSON streams the values encoded by the function as newline-delimited JSON,
+// the encoding fails once the client is gone.
+//
+//	bird.NDJSON(actor, http.StatusOK, func(enc bird.NDJSONEncoder) error {
+//	    for rows.Next() {
+//	        if err := enc.Encode(row); err != nil {
+//	            return err
+//	        }
+//	    }
+//	    return nil
+//	})
+func NDJSON(actor Actor, statusCode int, encode func(enc NDJSONEncoder) error) error {
+	return actor.Stream(statusCode, ContentTypeNDJSON, func(w io.Writer) error {
+		return encode(NDJSONEncoder{enc: json.NewEncoder(w)})
+	})
+}
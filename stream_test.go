@@ -0,0 +1,83 @@
+package bird
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+// flushRecorder counts the flushes of the response
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushes++
+	r.ResponseRecorder.Flush()
+}
+
+func TestStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		var streamErr error
+		r.ON("/rows", func(actor Actor) {
+			streamErr = NDJSON(actor, http.StatusOK, func(enc NDJSONEncoder) error {
+				for i := 1; i <= 3; i++ {
+					if err := enc.Encode(map[string]int{"row": i}); err != nil {
+						return err
+					}
+					if rec.flushes != i || strings.Count(rec.Body.String(), "\n") != i {
+						t.Fatal(name, "write not flushed", rec.flushes, rec.Body.String())
+					}
+				}
+				return nil
+			})
+		}).Prepare(http.MethodGet)
+		r.ON("/fail", func(actor Actor) {
+			if streamErr = actor.Stream(http.StatusOK, ContentTypeNDJSON, func(w io.Writer) error {
+				return errors.New("query failed")
+			}); streamErr != nil {
+				actor.Write(http.StatusInternalServerError, UnknownError(streamErr))
+			}
+		}).Prepare(http.MethodGet)
+		r.ON("/gone", func(actor Actor) {
+			streamErr = actor.Stream(http.StatusOK, ContentTypeNDJSON, func(w io.Writer) error {
+				_, err := w.Write([]byte("{}\n"))
+				return err
+			})
+		}).Prepare(http.MethodGet)
+		h := r.HttpHandler()
+
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/rows", nil))
+		if streamErr != nil || rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != ContentTypeNDJSON || rec.Body.String() != "{\"row\":1}\n{\"row\":2}\n{\"row\":3}\n" {
+			t.Fatal(name, "stream", streamErr, rec.Code, rec.Header(), rec.Body.String())
+		}
+
+		failed := httptest.NewRecorder()
+		h.ServeHTTP(failed, httptest.NewRequest(http.MethodGet, "/fail", nil))
+		if streamErr == nil || failed.Code != http.StatusInternalServerError || failed.Header().Get("Content-Type") == ContentTypeNDJSON {
+			t.Fatal(name, "stream written before failing", failed.Code, failed.Header())
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		gone := httptest.NewRecorder()
+		h.ServeHTTP(gone, httptest.NewRequest(http.MethodGet, "/gone", nil).WithContext(ctx))
+		if !errors.Is(streamErr, context.Canceled) || gone.Body.Len() != 0 {
+			t.Fatal(name, "stream written to the client gone", streamErr, gone.Body.String())
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package bird
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dev-mockingbird/logf"
+)
+
+// CertReloader serves the certificate of the files, and reloads it when the files change
+type CertReloader struct {
+	certFile, keyFile string
+	interval          time.Duration
+	mu                sync.RWMutex
+	cert              *tls.Certificate
+	modTime           time.Time
+	checked           time.Time
+	logger            logf.Logger
+}
+
+// NewCertReloader loads the certificate, the files are checked for changes
+// at most once per interval on the handshakes
+func NewCertReloader(certFile, keyFile string, interval time.Duration, logger logf.Logger) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile, interval: interval, logger: logger}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	cert, due := r.cert, time.Since(r.checked) >= r.interval
+	r.mu.RUnlock()
+	if due {
+		if err := r.reload(); err != nil {
+			// keep serving the last good certificate
+			r.logger.Logf(logf.Error, "reload certificate: %s", err.Error())
+			return cert, nil
+		}
+		r.mu.RLock()
+		cert = r.cert
+		r.mu.RUnlock()
+	}
+	return cert, nil
+}
+
+func (r *CertReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checked = time.Now()
+	modTime, err := latestModTime(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	if r.cert != nil && !modTime.After(r.modTime) {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	if r.cert != nil {
+		r.logger.Logf(logf.Info, "certificate %s reloaded", r.certFile)
+	}
+	r.cert, r.modTime = &cert, modTime
+	return nil
+}
+
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// TLSFiles serves TLS with the certificate and the key files,
+// they are reloaded when changed, e.g. renewed by cert-manager
+func TLSFiles(certFile, keyFile string) ServeOption {
+	return func(c *serveConfig) {
+		c.certFile, c.keyFile = certFile, keyFile
+	}
+}
+
+// ClientCAFiles verifies the client certificates with the CAs (mutual TLS),
+// the clients without a certificate are rejected if required
+func ClientCAFiles(required bool, caFiles ...string) ServeOption {
+	return func(c *serveConfig) {
+		pool := x509.NewCertPool()
+		for _, file := range caFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				c.err = err
+				return
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				c.err = fmt.Errorf("no certificate found in %s", file)
+				return
+			}
+		}
+		c.clientCAs = pool
+		c.clientAuth = tls.VerifyClientCertIfGiven
+		if required {
+			c.clientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+}
+
+// DisableHTTP2 serves HTTP/1.1 only over TLS, HTTP/2 is negotiated by ALPN by default
+func DisableHTTP2() ServeOption {
+	return func(c *serveConfig) {
+		c.disableHTTP2 = true
+	}
+}
+
+// prepareTLS loads the certificate files and sets the client CAs and the ALPN protocols
+// on a clone of the TLS config, the config of TLSConfig may be shared by the caller
+func (c *serveConfig) prepareTLS() error {
+	if c.tlsConfig == nil {
+		if c.certFile == "" && c.clientCAs == nil {
+			return nil
+		}
+		c.tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	} else {
+		c.tlsConfig = c.tlsConfig.Clone()
+	}
+	if c.clientCAs != nil {
+		c.tlsConfig.ClientCAs = c.clientCAs
+		c.tlsConfig.ClientAuth = c.clientAuth
+	}
+	if c.certFile != "" {
+		reloader, err := NewCertReloader(c.certFile, c.keyFile, 10*time.Second, c.logger)
+		if err != nil {
+			return err
+		}
+		c.tlsConfig.GetCertificate = reloader.GetCertificate
+	}
+	if len(c.tlsConfig.NextProtos) > 0 {
+		return nil
+	}
+	if c.disableHTTP2 {
+		c.tlsConfig.NextProtos = []string{"http/1.1"}
+		return nil
+	}
+	c.tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+	return nil
+}
+
+// ClientIdentity is the identity of a verified client certificate
+type ClientIdentity struct {
+	CommonName   string
+	Organization []string
+	DNSNames     []string
+	URIs         []string
+	SerialNumber string
+	Certificate  *x509.Certificate
+}
+
+// String returns the SPIFFE ID if the certificate has one, the common name otherwise
+func (id ClientIdentity) String() string {
+	for _, uri := range id.URIs {
+		if strings.HasPrefix(uri, "spiffe://") {
+			return uri
+		}
+	}
+	return id.CommonName
+}
+
+// ClientIdentityOf returns the identity of the verified client certificate of the request
+func ClientIdentityOf(req *http.Request) (ClientIdentity, bool) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return ClientIdentity{}, false
+	}
+	cert := req.TLS.VerifiedChains[0][0]
+	id := ClientIdentity{
+		CommonName:   cert.Subject.CommonName,
+		Organization: cert.Subject.Organization,
+		DNSNames:     cert.DNSNames,
+		SerialNumber: cert.SerialNumber.String(),
+		Certificate:  cert,
+	}
+	for _, uri := range cert.URIs {
+		id.URIs = append(id.URIs, uri.String())
+	}
+	return id, true
+}
+
+// ClientCertPrincipal sets the ClientIdentity of the verified client certificate
+// as the principal, the requests without one are rejected with 401 if required.
+//
+//	bird.Serve(ctx, r, bird.TLSFiles(cert, key), bird.ClientCAFiles(false, ca))
+//	r.Use(bird.ClientCertPrincipal(true))
+func ClientCertPrincipal(required bool) HandleFunc {
+	return func(actor Actor) {
+		id, ok := ClientIdentityOf(actor.GetRequest())
+		if !ok {
+			if required {
+				actor.Logger().Logf(logf.Trace, "reject request: no verified client certificate")
+				actor.Write(http.StatusUnauthorized, Unauthorized(errors.New("no verified client certificate"), "client certificate required"))
+				return
+			}
+			actor.Next()
+			return
+		}
+		actor.Set(PrincipalKey, id)
+		actor.Next()
+	}
+}
@@ -0,0 +1,221 @@
+package bird
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/quic-go/quic-go/http3"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func issueTestCert(t *testing.T, cn string, serial int64, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) writeFiles(t *testing.T, dir, name string) (string, string) {
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	keyDer, _ := x509.MarshalECPrivateKey(c.key)
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return certFile, keyFile
+}
+
+func TestServeMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueTestCert(t, "ca", 1, nil, x509.ExtKeyUsageAny)
+	caFile, _ := ca.writeFiles(t, dir, "ca")
+	certFile, keyFile := issueTestCert(t, "server", 2, ca, x509.ExtKeyUsageServerAuth).writeFiles(t, dir, "server")
+	client := issueTestCert(t, "client-service", 3, ca, x509.ExtKeyUsageClientAuth)
+
+	gin.SetMode(gin.TestMode)
+	r := GinRouter(gin.New(), logf.New())
+	r.Use(ClientCertPrincipal(true))
+	r.ON("/whoami", func(actor Actor) {
+		principal, _ := actor.Get(PrincipalKey)
+		actor.Write(http.StatusOK, OK(principal.(ClientIdentity).String()))
+	}).Prepare(http.MethodGet)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Serve(ctx, r, Listener(ln), TLSFiles(certFile, keyFile), ClientCAFiles(true, caFile), ServeLogger(logf.New(logf.LogLevel(logf.Error))))
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	tr := &http.Transport{
+		ForceAttemptHTTP2: true,
+		TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{{Certificate: [][]byte{client.der}, PrivateKey: client.key}},
+		},
+	}
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = (&http.Client{Transport: tr}).Get("https://" + ln.Addr().String() + "/whoami"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Data string `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Data != "client-service" {
+		t.Fatal("client identity not exposed as principal")
+	}
+	if resp.ProtoMajor != 2 {
+		t.Fatal("http2 not negotiated")
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueTestCert(t, "ca", 1, nil, x509.ExtKeyUsageAny)
+	certFile, keyFile := issueTestCert(t, "server", 2, ca, x509.ExtKeyUsageServerAuth).writeFiles(t, dir, "server")
+	reloader, err := NewCertReloader(certFile, keyFile, 0, logf.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	issueTestCert(t, "server", 3, ca, x509.ExtKeyUsageServerAuth).writeFiles(t, dir, "server")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	cert, _ := reloader.GetCertificate(nil)
+	if parsed, _ := x509.ParseCertificate(cert.Certificate[0]); parsed.SerialNumber.Int64() != 3 {
+		t.Fatal("certificate not reloaded")
+	}
+}
+
+func TestServeHTTP3(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueTestCert(t, "ca", 1, nil, x509.ExtKeyUsageAny)
+	certFile, keyFile := issueTestCert(t, "server", 2, ca, x509.ExtKeyUsageServerAuth).writeFiles(t, dir, "server")
+
+	gin.SetMode(gin.TestMode)
+	r := GinRouter(gin.New(), logf.New())
+	r.ON("/proto", func(actor Actor) {
+		actor.Write(http.StatusOK, OK(actor.GetRequest().Proto))
+	}).Prepare(http.MethodGet)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Serve(ctx, r, Listener(ln), TLSFiles(certFile, keyFile), HTTP3(addr), ServeLogger(logf.New(logf.LogLevel(logf.Error))))
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Get("https://" + addr + "/proto"); err == nil && resp.Header.Get("Alt-Svc") != "" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if !strings.Contains(resp.Header.Get("Alt-Svc"), "h3=") {
+		t.Fatal("HTTP/3 not advertised")
+	}
+	h3 := &http3.RoundTripper{TLSClientConfig: &tls.Config{RootCAs: roots}}
+	defer h3.Close()
+	if resp, err = (&http.Client{Transport: h3}).Get("https://" + addr + "/proto"); err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Data string `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Data != "HTTP/3.0" {
+		t.Fatal("request not served over HTTP/3")
+	}
+}
+
+func TestTLSConfigNotShared(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueTestCert(t, "ca", 1, nil, x509.ExtKeyUsageAny)
+	caFile, _ := ca.writeFiles(t, dir, "ca")
+	certFile, keyFile := issueTestCert(t, "server", 2, ca, x509.ExtKeyUsageServerAuth).writeFiles(t, dir, "server")
+	shared := &tls.Config{MinVersion: tls.VersionTLS13}
+	c := serveConfig{logger: logf.New()}
+	for _, apply := range []ServeOption{TLSConfig(shared), TLSFiles(certFile, keyFile), ClientCAFiles(true, caFile)} {
+		apply(&c)
+	}
+	if err := c.prepareTLS(); err != nil {
+		t.Fatal(err)
+	}
+	if shared.GetCertificate != nil || shared.ClientCAs != nil || shared.ClientAuth != tls.NoClientCert || shared.NextProtos != nil {
+		t.Fatal("shared TLS config changed")
+	}
+	if c.tlsConfig == shared || c.tlsConfig.GetCertificate == nil || c.tlsConfig.ClientCAs == nil ||
+		c.tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert || c.tlsConfig.MinVersion != tls.VersionTLS13 {
+		t.Fatal("TLS options not applied to the clone")
+	}
+}
@@ -0,0 +1,180 @@
+package bird
+
+import (
+	"context"
+
+	"github.com/dev-mockingbird/validate"
+	microerrors "go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/dev-mockingbird/bird"
+
+// the attribute of the bird response code on the spans
+const ResponseCodeAttribute = attribute.Key("bird.code")
+
+type tracingConfig struct {
+	provider         trace.TracerProvider
+	propagator       propagation.TextMapPropagator
+	traceAsRequestId bool
+}
+
+type TracingOption func(*tracingConfig)
+
+// TracingProvider sets the tracer provider, the global one is used by default
+func TracingProvider(provider trace.TracerProvider) TracingOption {
+	return func(c *tracingConfig) {
+		c.provider = provider
+	}
+}
+
+// TracingPropagator sets the propagator, W3C traceparent and baggage are used by default
+func TracingPropagator(propagator propagation.TextMapPropagator) TracingOption {
+	return func(c *tracingConfig) {
+		c.propagator = propagator
+	}
+}
+
+// requestIdGeneratedKey marks the Request-Id generated by the router, not sent by the client
+const requestIdGeneratedKey = "bird.request_id_generated"
+
+// TraceIdAsRequestId makes the trace id the RequestId of the request, the Request-Id sent
+// by the client is kept. the middlewares before Tracing log the Request-Id generated before
+func TraceIdAsRequestId() TracingOption {
+	return func(c *tracingConfig) {
+		c.traceAsRequestId = true
+	}
+}
+
+func newTracingConfig(opts ...TracingOption) tracingConfig {
+	c := tracingConfig{
+		provider:   otel.GetTracerProvider(),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}
+	for _, apply := range opts {
+		apply(&c)
+	}
+	return c
+}
+
+// Tracing starts a server span named after the route pattern for every request,
+// the span is carried by the context of Actor.GetRequest for the handlers after it.
+// the span fails with the code of the response body if it isn't ok.
+//
+//	r.Use(bird.Tracing(bird.TraceIdAsRequestId()))
+func Tracing(opts ...TracingOption) HandleFunc {
+	c := newTracingConfig(opts...)
+	tracer := c.provider.Tracer(tracerName)
+	return func(actor Actor) {
+		req := actor.GetRequest()
+		ctx := c.propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		route := routePattern(actor)
+		name := req.Method
+		if route != "" {
+			name += " " + route
+		}
+		ctx, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(req.Method),
+				semconv.HTTPRouteKey.String(route),
+				semconv.HTTPTargetKey.String(req.URL.RequestURI()),
+				semconv.HTTPClientIPKey.String(ClientIP(actor)),
+				semconv.HTTPUserAgentKey.String(req.UserAgent()),
+			),
+		)
+		defer span.End()
+		if generated, _ := actor.Get(requestIdGeneratedKey); c.traceAsRequestId && generated == true && span.SpanContext().HasTraceID() {
+			req.Header.Set("Request-Id", span.SpanContext().TraceID().String())
+		}
+		span.SetAttributes(attribute.String("bird.request_id", req.Header.Get("Request-Id")))
+		setRequest(actor, req.WithContext(ctx))
+		actor.Next()
+		status := responseStatus(actor)
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+		code := ResponseCode(actor)
+		if code != "" {
+			span.SetAttributes(ResponseCodeAttribute.String(code))
+		}
+		if code != "" && code != CodeOK {
+			span.SetStatus(codes.Error, code)
+			return
+		}
+		if status >= 500 {
+			span.SetStatus(codes.Error, "")
+		}
+	}
+}
+
+// TraceForwarder starts a client span around the forward, and injects the span into
+// the go-micro metadata of the request context. the forward must call the backend
+// with the context of Actor.GetRequest to propagate it.
+//
+//	forwarder := bird.TraceForwarder(bird.GetForwarder())
+//	forwarder.Forward(actor, &req, func() error {
+//	    rsp, err = client.Call(actor.GetRequest().Context(), &req)
+//	    return err
+//	})
+func TraceForwarder(forwarder Forwarder, opts ...TracingOption) Forwarder {
+	c := newTracingConfig(opts...)
+	tracer := c.provider.Tracer(tracerName)
+	return Forward(func(actor Actor, creq any, forward func() error, rules ...validate.Rules) error {
+		return forwarder.Forward(actor, creq, func() error {
+			req := actor.GetRequest()
+			name := "forward"
+			if route := routePattern(actor); route != "" {
+				name += " " + route
+			}
+			ctx, span := tracer.Start(req.Context(), name, trace.WithSpanKind(trace.SpanKindClient))
+			defer span.End()
+			setRequest(actor, req.WithContext(injectMetadata(ctx, c.propagator)))
+			err := forward()
+			setRequest(actor, req)
+			if err != nil {
+				span.RecordError(err)
+				code := CodeUnkownError
+				if e, ok := err.(*microerrors.Error); ok && e.Status != "" {
+					code = e.Status
+				}
+				span.SetAttributes(ResponseCodeAttribute.String(code))
+				span.SetStatus(codes.Error, code)
+			}
+			return err
+		}, rules...)
+	})
+}
+
+// injectMetadata injects the span of the context into the go-micro metadata
+func injectMetadata(ctx context.Context, propagator propagation.TextMapPropagator) context.Context {
+	md, ok := metadata.FromContext(ctx)
+	if !ok {
+		md = make(metadata.Metadata)
+	}
+	propagator.Inject(ctx, metadataCarrier(md))
+	return metadata.NewContext(ctx, md)
+}
+
+type metadataCarrier metadata.Metadata
+
+func (c metadataCarrier) Get(key string) string {
+	v, _ := metadata.Metadata(c).Get(key)
+	return v
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.Metadata(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
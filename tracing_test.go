@@ -0,0 +1,73 @@
+package bird
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+func TestTracing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		var requestId string
+		r.Use(Tracing(TracingProvider(provider), TraceIdAsRequestId()))
+		r.ON("/users/:id", func(actor Actor) {
+			requestId = actor.RequestId()
+			actor.Write(http.StatusNotFound, ErrorOccurred(errors.New("user not found"), "user-not-found"))
+		}).Prepare(http.MethodGet)
+		req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+		req.Header.Set("traceparent", traceparent)
+		r.HttpHandler().ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		if len(spans) != 1 {
+			t.Fatal(name, "spans", len(spans))
+		}
+		span := spans[0]
+		if span.Name() != "GET /users/:id" {
+			t.Fatal(name, "span name", span.Name())
+		}
+		if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+			t.Fatal(name, "incoming trace not propagated", span.SpanContext().TraceID(), span.Parent().SpanID())
+		}
+		if requestId != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Fatal(name, "trace id not the request id", requestId)
+		}
+		attrs := map[attribute.Key]attribute.Value{}
+		for _, attr := range span.Attributes() {
+			attrs[attr.Key] = attr.Value
+		}
+		if attrs[semconv.HTTPRouteKey].AsString() != "/users/:id" || attrs[semconv.HTTPStatusCodeKey].AsInt64() != http.StatusNotFound ||
+			attrs[ResponseCodeAttribute].AsString() != "user-not-found" {
+			t.Fatal(name, "span attributes", span.Attributes())
+		}
+		if span.Status().Code != codes.Error || span.Status().Description != "user-not-found" {
+			t.Fatal(name, "span status", span.Status())
+		}
+		// the Request-Id of the client is kept
+		req = httptest.NewRequest(http.MethodGet, "/users/7", nil)
+		req.Header.Set("traceparent", traceparent)
+		req.Header.Set("Request-Id", "client-1")
+		r.HttpHandler().ServeHTTP(httptest.NewRecorder(), req)
+		if requestId != "client-1" {
+			t.Fatal(name, "request id of the client replaced", requestId)
+		}
+
+	}
+}
@@ -0,0 +1,362 @@
+package bird
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	birderrors "github.com/dev-mockingbird/errors"
+	"github.com/dev-mockingbird/logf"
+)
+
+const uploadConfigKey = "bird.upload-config"
+
+type uploadConfig struct {
+	maxFileSize    int64
+	maxRequestSize int64
+	maxMemory      int64
+	types          []string
+}
+
+func defaultUploadConfig() *uploadConfig {
+	return &uploadConfig{maxFileSize: 32 << 20, maxRequestSize: 64 << 20, maxMemory: 8 << 20}
+}
+
+type UploadOption func(*uploadConfig)
+
+// UploadMaxFileSize limits the size of a file, 32MB by default
+func UploadMaxFileSize(bytes int64) UploadOption {
+	return func(c *uploadConfig) {
+		c.maxFileSize = bytes
+	}
+}
+
+// UploadMaxRequestSize limits the size of the multipart body, 64MB by default
+func UploadMaxRequestSize(bytes int64) UploadOption {
+	return func(c *uploadConfig) {
+		c.maxRequestSize = bytes
+	}
+}
+
+// UploadMaxMemory is the size of the files kept in memory, the rest is stored
+// in temporary files, 8MB by default
+func UploadMaxMemory(bytes int64) UploadOption {
+	return func(c *uploadConfig) {
+		c.maxMemory = bytes
+	}
+}
+
+// UploadTypes allows the files of the types sniffed from their content, e.g. image/* or
+// application/pdf, all types are allowed by default
+func UploadTypes(types ...string) UploadOption {
+	return func(c *uploadConfig) {
+		c.types = types
+	}
+}
+
+// Uploads configures the uploads of Actor.File, Actor.Files and StreamFiles
+// for the handlers after it, the files and the bodies too large fail with the
+// errors tagged payload-too-large, see BindErrorStatus
+//
+//	r.ON("/avatar", bird.Uploads(bird.UploadMaxFileSize(1<<20), bird.UploadTypes("image/*")), upload).Prepare(http.MethodPost)
+func Uploads(opts ...UploadOption) HandleFunc {
+	c := defaultUploadConfig()
+	for _, apply := range opts {
+		apply(c)
+	}
+	return func(actor Actor) {
+		actor.Set(uploadConfigKey, c)
+		actor.Next()
+	}
+}
+
+func uploadConfigOf(actor Actor) *uploadConfig {
+	if c, ok := actor.Get(uploadConfigKey); ok {
+		if c, ok := c.(*uploadConfig); ok {
+			return c
+		}
+	}
+	return defaultUploadConfig()
+}
+
+// the errors of the uploads are tagged with the code of the response
+var (
+	ErrNotMultipart   = birderrors.New("request isn't multipart", CodeBadFormat)
+	ErrUploadTooLarge = birderrors.New("upload too large", CodePayloadTooLarge)
+)
+
+// UploadedFile is a file of the multipart form
+type UploadedFile struct {
+	Field    string
+	Filename string
+	Size     int64
+	// ContentType is sniffed from the content, the one declared by the client is in Header
+	ContentType string
+	Header      textproto.MIMEHeader
+	fh          *multipart.FileHeader
+}
+
+func (f *UploadedFile) Open() (multipart.File, error) {
+	return f.fh.Open()
+}
+
+// Save saves the file in the storage under the key
+func (f *UploadedFile) Save(ctx context.Context, storage Storage, key string) error {
+	file, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return storage.Save(ctx, key, file, f.Size, f.ContentType)
+}
+
+// formFiles implements Actor.Files on the request of the actor
+func formFiles(actor Actor, name string) ([]*UploadedFile, error) {
+	c := uploadConfigOf(actor)
+	req := actor.GetRequest()
+	if req.MultipartForm == nil {
+		req.Body = http.MaxBytesReader(actor.GetResponseWriter(), req.Body, c.maxRequestSize)
+		if err := req.ParseMultipartForm(c.maxMemory); err != nil {
+			actor.Logger().Logf(logf.Debug, "parse multipart form: %s", err.Error())
+			return nil, uploadError(err)
+		}
+	} else if formSize(req.MultipartForm) > c.maxRequestSize {
+		// the form parsed before, e.g. by a middleware, is limited as well
+		return nil, ErrUploadTooLarge
+	}
+	headers := req.MultipartForm.File[name]
+	if len(headers) == 0 {
+		return nil, birderrors.New(fmt.Sprintf("file %s is required", name), CodeInvalidArguments)
+	}
+	files := make([]*UploadedFile, 0, len(headers))
+	for _, fh := range headers {
+		f, err := c.check(name, fh)
+		if err != nil {
+			actor.Logger().Logf(logf.Debug, "reject file %s: %s", fh.Filename, err.Error())
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// formSize returns the size of the values and the files of the form
+func formSize(form *multipart.Form) int64 {
+	var size int64
+	for key, values := range form.Value {
+		for _, value := range values {
+			size += int64(len(key) + len(value))
+		}
+	}
+	for _, headers := range form.File {
+		for _, fh := range headers {
+			size += fh.Size
+		}
+	}
+	return size
+}
+
+// formFile implements Actor.File on the request of the actor
+func formFile(actor Actor, name string) (*UploadedFile, error) {
+	files, err := formFiles(actor, name)
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+func uploadError(err error) error {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case len(birderrors.Tags(err)) > 0:
+		return err
+	case errors.As(err, &tooLarge):
+		return ErrUploadTooLarge
+	case errors.Is(err, http.ErrNotMultipart), errors.Is(err, http.ErrMissingBoundary):
+		return ErrNotMultipart
+	}
+	return birderrors.Tag(err, CodeBadFormat)
+}
+
+// check checks the size and the sniffed type of the file
+func (c *uploadConfig) check(field string, fh *multipart.FileHeader) (*UploadedFile, error) {
+	if fh.Size > c.maxFileSize {
+		return nil, birderrors.New(fmt.Sprintf("file %s exceeds %d bytes", fh.Filename, c.maxFileSize), CodePayloadTooLarge)
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !c.allowType(contentType) {
+		return nil, birderrors.New(fmt.Sprintf("file %s of type %s isn't allowed", fh.Filename, mediaType(contentType)), CodeInvalidArguments)
+	}
+	return &UploadedFile{
+		Field:       field,
+		Filename:    fh.Filename,
+		Size:        fh.Size,
+		ContentType: contentType,
+		Header:      fh.Header,
+		fh:          fh,
+	}, nil
+}
+
+func (c *uploadConfig) allowType(contentType string) bool {
+	return matchMediaType(c.types, contentType)
+}
+
+// matchMediaType matches the media type of the content type with the patterns,
+// e.g. image/*, no pattern matches all
+func matchMediaType(patterns []string, contentType string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	typ := mediaType(contentType)
+	for _, pattern := range patterns {
+		if pattern == typ || pattern == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(typ, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func mediaType(contentType string) string {
+	if typ, _, err := mime.ParseMediaType(contentType); err == nil {
+		return typ
+	}
+	return contentType
+}
+
+// FilePart is a file streamed from the multipart body
+type FilePart struct {
+	Field    string
+	Filename string
+	// ContentType is sniffed from the content
+	ContentType string
+	Header      textproto.MIMEHeader
+	io.Reader
+}
+
+// StreamFiles reads the files of the multipart body one by one without buffering them,
+// e.g. to save the large files in a storage. reading a file fails once it exceeds the max
+// file size, the other fields are skipped.
+//
+//	err := bird.StreamFiles(actor, func(part *bird.FilePart) error {
+//	    return storage.Save(ctx, uuid.NewString(), part, -1, part.ContentType)
+//	})
+func StreamFiles(actor Actor, handle func(part *FilePart) error) error {
+	c := uploadConfigOf(actor)
+	req := actor.GetRequest()
+	req.Body = http.MaxBytesReader(actor.GetResponseWriter(), req.Body, c.maxRequestSize)
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return uploadError(err)
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return uploadError(err)
+		}
+		if part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		tooLarge := birderrors.New(fmt.Sprintf("file %s is too large", part.FileName()), CodePayloadTooLarge)
+		buffered := bufio.NewReaderSize(&limitedReader{r: part, n: c.maxFileSize, err: tooLarge}, 512)
+		head, err := buffered.Peek(512)
+		if err != nil && err != io.EOF {
+			part.Close()
+			return uploadError(err)
+		}
+		contentType := http.DetectContentType(head)
+		if !c.allowType(contentType) {
+			part.Close()
+			return birderrors.New(fmt.Sprintf("file %s of type %s isn't allowed", part.FileName(), mediaType(contentType)), CodeInvalidArguments)
+		}
+		err = handle(&FilePart{
+			Field:       part.FormName(),
+			Filename:    part.FileName(),
+			ContentType: contentType,
+			Header:      part.Header,
+			Reader:      buffered,
+		})
+		part.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// limitedReader fails with err once more than n bytes are read
+type limitedReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, l.err
+	}
+	return n, err
+}
+
+// FileRule validates the uploaded files with Actor.Validate, for a *UploadedFile
+// or a []*UploadedFile field
+//
+//	actor.Validate(&form, validate.Rules{
+//	    ".Avatar": bird.FileRule(1<<20, "image/png", "image/jpeg"),
+//	})
+func FileRule(maxSize int64, types ...string) func(any) error {
+	check := func(f *UploadedFile) error {
+		if f == nil {
+			return errors.New("file is required")
+		}
+		name := f.Filename
+		if name == "" {
+			name = f.Field
+		}
+		if maxSize > 0 && f.Size > maxSize {
+			return fmt.Errorf("file %s exceeds %d bytes", name, maxSize)
+		}
+		if !matchMediaType(types, f.ContentType) {
+			return fmt.Errorf("file %s of type %s isn't allowed", name, mediaType(f.ContentType))
+		}
+		return nil
+	}
+	return func(val any) error {
+		switch f := val.(type) {
+		case *UploadedFile:
+			return check(f)
+		case []*UploadedFile:
+			for _, file := range f {
+				if err := check(file); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return fmt.Errorf("%T isn't an uploaded file", val)
+	}
+}
@@ -0,0 +1,262 @@
+package bird
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gorilla/websocket"
+)
+
+// the message types of Conn.ReadMessage and Conn.WriteMessage
+const (
+	TextMessage   = websocket.TextMessage
+	BinaryMessage = websocket.BinaryMessage
+)
+
+// Conn is a websocket connection upgraded from a request, it carries the data of the
+// actor of the request, e.g. the principal set by the authentication middlewares.
+// the writes are safe for concurrent use, the reads aren't.
+type Conn interface {
+	ReadJSON(v any) error
+	WriteJSON(v any) error
+	ReadMessage() (messageType int, data []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	// Close sends the close frame with the code and the reason, then closes the connection
+	Close(code int, reason string) error
+	// Context is done when the connection is closed, the connection is closed with
+	// the going away code when the server shuts down
+	Context() context.Context
+	RequestId() string
+	Get(key string) (any, bool)
+	GetRequest() *http.Request
+	Logger() logf.Logger
+}
+
+// IsCloseError reports whether the error is a close frame of the peer with one of the codes
+func IsCloseError(err error, codes ...int) bool {
+	return websocket.IsCloseError(err, codes...)
+}
+
+type wsConfig struct {
+	upgrader     websocket.Upgrader
+	readLimit    int64
+	pingInterval time.Duration
+	writeTimeout time.Duration
+}
+
+type WSOption func(*wsConfig)
+
+// WSReadLimit limits the size of a message read, 1MB by default,
+// the connection is closed with 1009 once exceeded
+func WSReadLimit(bytes int64) WSOption {
+	return func(c *wsConfig) {
+		c.readLimit = bytes
+	}
+}
+
+// WSPingInterval pings the peer every interval, 30 seconds by default,
+// the connection is closed if the peer doesn't respond in two intervals
+func WSPingInterval(interval time.Duration) WSOption {
+	return func(c *wsConfig) {
+		c.pingInterval = interval
+	}
+}
+
+// WSWriteTimeout is the deadline of a write, 10 seconds by default
+func WSWriteTimeout(timeout time.Duration) WSOption {
+	return func(c *wsConfig) {
+		c.writeTimeout = timeout
+	}
+}
+
+// WSCompression negotiates the per-message compression with the peer
+func WSCompression() WSOption {
+	return func(c *wsConfig) {
+		c.upgrader.EnableCompression = true
+	}
+}
+
+// WSCheckOrigin overrides the origin check, only the same origin is allowed by default
+func WSCheckOrigin(check func(req *http.Request) bool) WSOption {
+	return func(c *wsConfig) {
+		c.upgrader.CheckOrigin = check
+	}
+}
+
+// WSSubprotocols sets the subprotocols supported in the order of preference
+func WSSubprotocols(protocols ...string) WSOption {
+	return func(c *wsConfig) {
+		c.upgrader.Subprotocols = protocols
+	}
+}
+
+// WebSocket upgrades the request and serves the connection by the function,
+// the connection is closed after the function returns. Router.WS routes it on GET.
+//
+//	r.Use(auth)
+//	r.WS("/chat", func(conn bird.Conn) {
+//	    principal, _ := conn.Get(bird.PrincipalKey)
+//	    for {
+//	        var msg ChatMessage
+//	        if err := conn.ReadJSON(&msg); err != nil {
+//	            return
+//	        }
+//	        conn.WriteJSON(reply(principal, msg))
+//	    }
+//	}, bird.WSReadLimit(64<<10))
+func WebSocket(handle func(conn Conn), opts ...WSOption) HandleFunc {
+	c := wsConfig{readLimit: 1 << 20, pingInterval: 30 * time.Second, writeTimeout: 10 * time.Second}
+	for _, apply := range opts {
+		apply(&c)
+	}
+	return func(actor Actor) {
+		upgrader := c.upgrader
+		upgrader.Error = func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+			actor.Logger().Logf(logf.Debug, "websocket upgrade: %s", reason.Error())
+			actor.Write(status, ErrorOccurred(reason, CodeBadFormat, reason.Error()))
+		}
+		ws, err := upgrader.Upgrade(actor.GetResponseWriter(), actor.GetRequest(), http.Header{"Request-Id": {actor.RequestId()}})
+		if err != nil {
+			return
+		}
+		abort(actor)
+		conn := newWSConn(actor, ws, c)
+		defer conn.close()
+		actor.Logger().Logf(logf.Trace, "websocket connected")
+		handle(conn)
+	}
+}
+
+type wsConn struct {
+	ws           *websocket.Conn
+	actor        Actor
+	writeTimeout time.Duration
+	mu           sync.Mutex
+	ctx          context.Context
+	cancel       context.CancelFunc
+}
+
+func newWSConn(actor Actor, ws *websocket.Conn, c wsConfig) *wsConn {
+	ctx, cancel := context.WithCancel(actor.GetRequest().Context())
+	conn := &wsConn{ws: ws, actor: actor, writeTimeout: c.writeTimeout, ctx: ctx, cancel: cancel}
+	ws.SetReadLimit(c.readLimit)
+	if c.upgrader.EnableCompression {
+		ws.EnableWriteCompression(true)
+	}
+	if c.pingInterval > 0 {
+		ws.SetReadDeadline(time.Now().Add(2 * c.pingInterval))
+		ws.SetPongHandler(func(string) error {
+			return ws.SetReadDeadline(time.Now().Add(2 * c.pingInterval))
+		})
+		go conn.keepalive(c.pingInterval)
+	}
+	go func() {
+		select {
+		case <-ServerShutdown(ctx):
+			conn.Close(websocket.CloseGoingAway, "server shutting down")
+		case <-ctx.Done():
+		}
+	}()
+	return conn
+}
+
+func (c *wsConn) keepalive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.writeTimeout)); err != nil {
+				c.cancel()
+				return
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *wsConn) ReadMessage() (int, []byte, error) {
+	messageType, data, err := c.ws.ReadMessage()
+	if err != nil {
+		// the connection can't be read after an error
+		c.cancel()
+		if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+			c.actor.Logger().Logf(logf.Debug, "websocket read: %s", err.Error())
+		}
+		return 0, nil, err
+	}
+	return messageType, data, nil
+}
+
+func (c *wsConn) ReadJSON(v any) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c *wsConn) WriteMessage(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ws.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	if err := c.ws.WriteMessage(messageType, data); err != nil {
+		c.cancel()
+		return err
+	}
+	return nil
+}
+
+func (c *wsConn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, data)
+}
+
+func (c *wsConn) Close(code int, reason string) error {
+	defer c.cancel()
+	err := c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(c.writeTimeout))
+	if err != nil && !errors.Is(err, websocket.ErrCloseSent) {
+		c.ws.Close()
+		return err
+	}
+	return c.ws.Close()
+}
+
+// close closes the connection normally if the handler didn't
+func (c *wsConn) close() {
+	if c.ctx.Err() == nil {
+		c.Close(websocket.CloseNormalClosure, "")
+	}
+	c.cancel()
+	c.ws.Close()
+	c.actor.Logger().Logf(logf.Trace, "websocket closed")
+}
+
+func (c *wsConn) Context() context.Context {
+	return c.ctx
+}
+
+func (c *wsConn) RequestId() string {
+	return c.actor.RequestId()
+}
+
+func (c *wsConn) Get(key string) (any, bool) {
+	return c.actor.Get(key)
+}
+
+func (c *wsConn) GetRequest() *http.Request {
+	return c.actor.GetRequest()
+}
+
+func (c *wsConn) Logger() logf.Logger {
+	return c.actor.Logger()
+}
@@ -0,0 +1,55 @@
+package bird
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dev-mockingbird/logf"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+func TestWebSocket(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, r := range map[string]Router{
+		"gin":  GinRouter(gin.New(), logf.New()),
+		"echo": EchoRouter(echo.New(), logf.New()),
+	} {
+		r.Use(func(actor Actor) {
+			actor.Set(PrincipalKey, "alice")
+			actor.Next()
+		})
+		r.WS("/echo", func(conn Conn) {
+			for {
+				var msg struct {
+					Text string `json:"text"`
+				}
+				if err := conn.ReadJSON(&msg); err != nil {
+					return
+				}
+				principal, _ := conn.Get(PrincipalKey)
+				conn.WriteJSON(map[string]any{"text": msg.Text, "from": principal})
+			}
+		}, WSReadLimit(64))
+		srv := httptest.NewServer(r.HttpHandler())
+		ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/echo", nil)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		var reply map[string]string
+		if err := ws.WriteJSON(map[string]string{"text": "hi"}); err != nil {
+			t.Fatal(name, err)
+		}
+		if err := ws.ReadJSON(&reply); err != nil || reply["text"] != "hi" || reply["from"] != "alice" {
+			t.Fatal(name, "message not echoed with the principal", reply, err)
+		}
+		ws.WriteJSON(map[string]string{"text": strings.Repeat("x", 100)})
+		if _, _, err := ws.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+			t.Fatal(name, "read limit not enforced", err)
+		}
+		ws.Close()
+		srv.Close()
+	}
+}